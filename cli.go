@@ -0,0 +1,480 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/LFroesch/gitty/internal/git"
+)
+
+// Exit codes for the non-interactive subcommands. These are part of the
+// scripting contract - don't renumber them.
+const (
+	exitOK          = 0 // success
+	exitError       = 1 // git failed or not a repository
+	exitUsage       = 2 // bad subcommand, flag or argument
+	exitNothingToDo = 3 // nothing staged / nothing to clean
+)
+
+const cliUsage = `Usage: gitty [command] [flags]
+
+Run without a command to start the TUI.
+
+Commands:
+  suggest                  Print ranked commit suggestions for staged changes
+  commit --auto            Commit staged changes with the top suggestion
+  commit -m <message>      Commit staged changes with a message
+  status [--json]          Print branch, staged/unstaged and ahead/behind counts
+  snapshot [--commits N]   Print the full repository state as versioned JSON
+  compare <range> [--json] Compare HEAD with a ref, or a..b (trees) / a...b (since merge base)
+  prompt [--format T]      Print a one-line status for shell prompts
+  hooks list               List available hooks and whether they're installed
+  hooks install <type>     Install a hook (conventional-commits, no-large-files, detect-secrets)
+  hooks remove <type>      Remove a hook
+  clean --dry-run          List untracked files that would be removed
+  clean --force            Remove untracked files
+  undo                     Undo the last destructive gitty action
+  undo --list              List the saved checkpoints, newest first
+
+Exit codes:
+  0  success
+  1  git error or not a repository
+  2  usage error
+  3  nothing to do (no staged changes, nothing to clean, nothing to undo)
+`
+
+type cliCommand func(repoPath string, args []string, stdout, stderr io.Writer) int
+
+// Commands that locate the repository themselves, skipping the upfront check
+var cliStandalone = map[string]cliCommand{
+	"prompt": cliPrompt,
+}
+
+var cliCommands = map[string]cliCommand{
+	"suggest":  cliSuggest,
+	"commit":   cliCommit,
+	"status":   cliStatus,
+	"snapshot": cliSnapshot,
+	"compare":  cliCompare,
+	"hooks":    cliHooks,
+	"clean":    cliClean,
+	"undo":     cliUndo,
+}
+
+// runCLI dispatches a subcommand and returns its exit code
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Fprint(stdout, cliUsage)
+		return exitOK
+	}
+
+	if command, ok := cliStandalone[name]; ok {
+		return command("", args[1:], stdout, stderr)
+	}
+
+	command, ok := cliCommands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "gitty: unknown command %q\n\n%s", name, cliUsage)
+		return exitUsage
+	}
+
+	repoPath, err := os.Getwd()
+	if err != nil || !git.IsRepo(repoPath) {
+		fmt.Fprintln(stderr, "gitty: not a git repository")
+		return exitError
+	}
+
+	return command(repoPath, args[1:], stdout, stderr)
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments, which the flag package doesn't do on its own
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// flagExit maps a flag parse error to an exit code; -h is not a failure
+func flagExit(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	return exitUsage
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("gitty "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+func writeJSON(w io.Writer, v any) int {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return exitError
+	}
+	return exitOK
+}
+
+// stagedChanges filters a change list down to entries with index changes
+func stagedChanges(changes []git.Change) []git.Change {
+	var staged []git.Change
+	for _, c := range changes {
+		if c.IsStaged() {
+			staged = append(staged, c)
+		}
+	}
+	return staged
+}
+
+// Subcommands
+
+func cliSuggest(repoPath string, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("suggest", stderr)
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return flagExit(err)
+	}
+
+	staged := stagedChanges(git.GetChanges(repoPath))
+	if len(staged) == 0 {
+		fmt.Fprintln(stderr, "gitty: no staged changes")
+		return exitNothingToDo
+	}
+
+	for _, s := range buildCommitSuggestions(staged) {
+		fmt.Fprintln(stdout, s.Message)
+	}
+	return exitOK
+}
+
+func cliCommit(repoPath string, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("commit", stderr)
+	auto := fs.Bool("auto", false, "commit with the top-ranked suggestion")
+	message := fs.String("m", "", "commit message")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return flagExit(err)
+	}
+	if *auto == (*message != "") {
+		fmt.Fprintln(stderr, "gitty: commit needs exactly one of --auto or -m <message>")
+		return exitUsage
+	}
+
+	staged := stagedChanges(git.GetChanges(repoPath))
+	if len(staged) == 0 {
+		fmt.Fprintln(stderr, "gitty: no staged changes to commit")
+		return exitNothingToDo
+	}
+
+	msg := *message
+	if *auto {
+		msg = buildCommitSuggestions(staged)[0].Message
+	}
+
+	output, err := git.Execute(repoPath, "commit", "-m", msg)
+	if err != nil {
+		fmt.Fprintf(stderr, "gitty: commit failed: %s", output)
+		return exitError
+	}
+
+	fmt.Fprintf(stdout, "%s %s\n", git.GetCurrentCommitHash(repoPath), msg)
+	return exitOK
+}
+
+func cliStatus(repoPath string, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("status", stderr)
+	asJSON := fs.Bool("json", false, "print JSON")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return flagExit(err)
+	}
+
+	status := git.GetStatus(repoPath)
+	if *asJSON {
+		return writeJSON(stdout, status)
+	}
+
+	fmt.Fprintf(stdout, "%s staged:%d unstaged:%d ahead:%d behind:%d\n",
+		status.Branch, status.StagedFiles, status.UnstagedFiles, status.Ahead, status.Behind)
+	return exitOK
+}
+
+func cliSnapshot(repoPath string, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("snapshot", stderr)
+	commits := fs.Int("commits", 10, "number of recent commits to include")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return flagExit(err)
+	}
+	if *commits < 0 {
+		fmt.Fprintln(stderr, "gitty: --commits must not be negative")
+		return exitUsage
+	}
+
+	return writeJSON(stdout, git.GetSnapshot(repoPath, *commits))
+}
+
+const defaultPromptFormat = `{{.Branch}}` +
+	`{{if .Operation}} ({{.Operation}}{{if .Progress}} {{.Progress}}{{end}}){{end}}` +
+	`{{if .Ahead}} ↑{{.Ahead}}{{end}}{{if .Behind}} ↓{{.Behind}}{{end}}` +
+	`{{if .Conflicts}} ⚠{{.Conflicts}}{{end}}{{if .Staged}} ✓{{.Staged}}{{end}}` +
+	`{{if .Unstaged}} ●{{.Unstaged}}{{end}}{{if .Untracked}} ?{{.Untracked}}{{end}}`
+
+// cliPrompt is called on every prompt redraw, so it avoids IsRepo's extra
+// git spawn and prints nothing outside a repository
+func cliPrompt(_ string, args []string, stdout, stderr io.Writer) int {
+	format := os.Getenv("GITTY_PROMPT_FORMAT")
+	if format == "" {
+		format = defaultPromptFormat
+	}
+
+	fs := newFlagSet("prompt", stderr)
+	fs.StringVar(&format, "format", format, "text/template over branch, counts and operation (env GITTY_PROMPT_FORMAT)")
+	maxAge := fs.Duration("cache", 2*time.Second, "reuse cached status this long while the index is unchanged (0 disables)")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return flagExit(err)
+	}
+
+	tmpl, err := template.New("prompt").Parse(format)
+	if err != nil {
+		fmt.Fprintf(stderr, "gitty: bad prompt format: %v\n", err)
+		return exitUsage
+	}
+
+	cwd, _ := os.Getwd()
+	repoPath, gitDir, ok := git.FindRepoRoot(cwd)
+	if !ok {
+		return exitError
+	}
+
+	status, err := git.GetPromptStatus(repoPath, gitDir, *maxAge)
+	if err != nil {
+		return exitError
+	}
+
+	if err := tmpl.Execute(stdout, status); err != nil {
+		fmt.Fprintf(stderr, "gitty: bad prompt format: %v\n", err)
+		return exitUsage
+	}
+	fmt.Fprintln(stdout)
+	return exitOK
+}
+
+func cliCompare(repoPath string, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("compare", stderr)
+	asJSON := fs.Bool("json", false, "print JSON")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return flagExit(err)
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(stderr, "gitty: compare needs exactly one ref or range")
+		return exitUsage
+	}
+
+	target, source, threeDot := git.ParseCompareRange(positional[0])
+	if source == "HEAD" {
+		source = git.GetBranchName(repoPath)
+	}
+	for _, ref := range []string{target, source} {
+		if git.ResolveRev(repoPath, ref) == "" {
+			fmt.Fprintf(stderr, "gitty: unknown ref %q\n", ref)
+			return exitError
+		}
+	}
+
+	comparison := git.GetBranchComparison(repoPath, source, target, threeDot)
+	if *asJSON {
+		return writeJSON(stdout, comparison)
+	}
+
+	fmt.Fprintf(stdout, "%s vs %s\n", comparison.SourceBranch, comparison.TargetBranch)
+	if comparison.MergeBase != nil {
+		fmt.Fprintf(stdout, "Merge base: %s %s\n", comparison.MergeBase.Hash, comparison.MergeBase.Message)
+	}
+	fmt.Fprintf(stdout, "\nAhead: %d commits\n", len(comparison.AheadCommits))
+	for _, c := range comparison.AheadCommits {
+		fmt.Fprintf(stdout, "  %s %s\n", c.Hash, c.Message)
+	}
+	fmt.Fprintf(stdout, "\nBehind: %d commits\n", len(comparison.BehindCommits))
+	for _, c := range comparison.BehindCommits {
+		fmt.Fprintf(stdout, "  %s %s\n", c.Hash, c.Message)
+	}
+	fmt.Fprintf(stdout, "\nFiles changed: %d, +%d -%d\n", len(comparison.Files), comparison.Insertions, comparison.Deletions)
+	for _, f := range comparison.Files {
+		name := f.Path
+		if f.OrigPath != "" {
+			name = f.OrigPath + " => " + f.Path
+		}
+		if f.Binary {
+			fmt.Fprintf(stdout, "  %11s %s\n", "binary", name)
+		} else {
+			fmt.Fprintf(stdout, "  %5s %-5s %s\n", fmt.Sprintf("+%d", f.Added), fmt.Sprintf("-%d", f.Deleted), name)
+		}
+	}
+
+	merge := comparison.Merge
+	switch {
+	case merge.Error != "":
+		fmt.Fprintf(stdout, "\nMerge: unknown (%s)\n", merge.Error)
+	case merge.Clean:
+		fmt.Fprintf(stdout, "\nMerge: clean\n")
+	default:
+		fmt.Fprintf(stdout, "\nMerge: %d conflicting files\n", len(merge.Conflicts))
+		for _, c := range merge.Conflicts {
+			fmt.Fprintf(stdout, "  %s (%s)\n", c.Path, c.Type)
+		}
+	}
+	return exitOK
+}
+
+func cliHooks(repoPath string, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "gitty: hooks needs a subcommand: list, install or remove")
+		return exitUsage
+	}
+
+	findHook := func(name string) (git.HookInfo, bool) {
+		for _, h := range git.AvailableHooks() {
+			if string(h.Type) == name {
+				return h, true
+			}
+		}
+		return git.HookInfo{}, false
+	}
+
+	switch args[0] {
+	case "list":
+		for _, h := range git.AvailableHooks() {
+			state := "not installed"
+			if git.IsHookInstalled(repoPath, h.HookName) {
+				state = "installed"
+			}
+			fmt.Fprintf(stdout, "%-22s %-11s %s\n", h.Type, h.HookName, state)
+		}
+		return exitOK
+
+	case "install", "remove":
+		if len(args) != 2 {
+			fmt.Fprintf(stderr, "gitty: hooks %s needs a hook type\n", args[0])
+			return exitUsage
+		}
+		hook, ok := findHook(args[1])
+		if !ok {
+			var types []string
+			for _, h := range git.AvailableHooks() {
+				types = append(types, string(h.Type))
+			}
+			fmt.Fprintf(stderr, "gitty: unknown hook %q (available: %s)\n", args[1], strings.Join(types, ", "))
+			return exitUsage
+		}
+
+		var err error
+		if args[0] == "install" {
+			err = git.InstallHookByType(repoPath, hook.Type)
+		} else {
+			err = git.RemoveHook(repoPath, hook.HookName)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "gitty: hooks %s failed: %v\n", args[0], err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "%sed %s (%s)\n", strings.TrimSuffix(args[0], "e"), hook.Type, hook.HookName)
+		return exitOK
+	}
+
+	fmt.Fprintf(stderr, "gitty: unknown hooks subcommand %q\n", args[0])
+	return exitUsage
+}
+
+func cliClean(repoPath string, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("clean", stderr)
+	dryRun := fs.Bool("dry-run", false, "list files that would be removed")
+	force := fs.Bool("force", false, "remove untracked files")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return flagExit(err)
+	}
+	if *dryRun == *force {
+		fmt.Fprintln(stderr, "gitty: clean needs exactly one of --dry-run or --force")
+		return exitUsage
+	}
+
+	files, err := git.CleanDryRun(repoPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "gitty: clean failed: %v\n", err)
+		return exitError
+	}
+	if len(files) == 0 {
+		return exitNothingToDo
+	}
+
+	if *force {
+		checkpoint, err := git.Checkpoint(repoPath, fmt.Sprintf("clean %d untracked files", len(files)), files)
+		if err != nil {
+			fmt.Fprintf(stderr, "gitty: clean cancelled: %v\n", err)
+			return exitError
+		}
+		if err := git.CleanForce(repoPath, files); err != nil {
+			git.DropCheckpoint(repoPath, checkpoint)
+			fmt.Fprintf(stderr, "gitty: clean failed: %v\n", err)
+			return exitError
+		}
+	}
+
+	for _, f := range files {
+		fmt.Fprintln(stdout, f)
+	}
+	return exitOK
+}
+
+func cliUndo(repoPath string, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("undo", stderr)
+	list := fs.Bool("list", false, "list checkpoints instead of undoing")
+	if _, err := parseInterspersed(fs, args); err != nil {
+		return flagExit(err)
+	}
+
+	if *list {
+		entries, err := git.GetJournal(repoPath)
+		if err != nil {
+			fmt.Fprintf(stderr, "gitty: %v\n", err)
+			return exitError
+		}
+		for i := len(entries) - 1; i >= 0; i-- {
+			e := entries[i]
+			state := ""
+			if e.Undone {
+				state = " (undone)"
+			}
+			fmt.Fprintf(stdout, "%s  %s%s\n", e.Time.Format("2006-01-02 15:04:05"), e.Action, state)
+		}
+		return exitOK
+	}
+
+	if _, ok := git.LastUndoable(repoPath); !ok {
+		fmt.Fprintln(stderr, "gitty: nothing to undo")
+		return exitNothingToDo
+	}
+	entry, err := git.UndoLast(repoPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "gitty: undo failed: %v\n", err)
+		return exitError
+	}
+	fmt.Fprintf(stdout, "Undid: %s\n", entry.Action)
+	return exitOK
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LFroesch/gitty/internal/diff"
+	"github.com/LFroesch/gitty/internal/git"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+)
+
+var (
+	diffAddWordStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("16")).
+				Background(lipgloss.Color("77")).
+				Bold(true)
+
+	diffRemoveWordStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("16")).
+				Background(lipgloss.Color("167")).
+				Bold(true)
+
+	diffLineNumStyle = lipgloss.NewStyle().
+				Foreground(lipgloss.Color("240"))
+
+	// Under syntax highlighting, changes are marked with backgrounds
+	diffAddBg        = lipgloss.AdaptiveColor{Light: "194", Dark: "22"}
+	diffAddWordBg    = lipgloss.AdaptiveColor{Light: "157", Dark: "28"}
+	diffRemoveBg     = lipgloss.AdaptiveColor{Light: "224", Dark: "52"}
+	diffRemoveWordBg = lipgloss.AdaptiveColor{Light: "217", Dark: "88"}
+)
+
+// diffSectionPrefix starts the titles between the staged and unstaged diffs
+// of a partially staged file
+const diffSectionPrefix = "▌ "
+
+type diffRenderKey struct {
+	content string
+	width   int
+	split   bool
+}
+
+// Views re-render on every message, so recently rendered diffs are kept
+// instead of re-parsing and re-running word diffs each frame
+var diffRenderCache = map[diffRenderKey][]string{}
+
+const diffRenderCacheSize = 8
+
+// renderDiffLines lays out unified diff output as display lines no wider
+// than width, unified or side by side
+func renderDiffLines(content string, width int, split bool) []string {
+	key := diffRenderKey{content: content, width: width, split: split}
+	if lines, ok := diffRenderCache[key]; ok {
+		return lines
+	}
+
+	parsed := diff.Parse(content)
+	syn := highlightDiff(parsed)
+	var lines []string
+	if split {
+		lines = renderSplitDiff(parsed, syn, width)
+	} else {
+		lines = renderUnifiedDiff(parsed, syn, width)
+	}
+
+	if len(diffRenderCache) >= diffRenderCacheSize {
+		clear(diffRenderCache)
+	}
+	diffRenderCache[key] = lines
+	return lines
+}
+
+func renderUnifiedDiff(lines []diff.Line, syn [][]span, width int) []string {
+	out := make([]string, 0, len(lines))
+	for i, line := range lines {
+		switch line.Kind {
+		case diff.Added:
+			out = append(out, diffAddStyle.Render("+")+fitPieces(diffPieces(line, syn[i]), width-1, false))
+		case diff.Removed:
+			out = append(out, diffRemoveStyle.Render("-")+fitPieces(diffPieces(line, syn[i]), width-1, false))
+		case diff.Context:
+			out = append(out, " "+fitPieces(diffPieces(line, syn[i]), width-1, false))
+		default:
+			out = append(out, fitPieces(diffPieces(line, nil), width, false))
+		}
+	}
+	return out
+}
+
+func renderSplitDiff(lines []diff.Line, syn [][]span, width int) []string {
+	// Each side: 4-digit line number, a space, then text; " │ " between sides
+	side := (width - 3) / 2
+	if side < 8 {
+		return renderUnifiedDiff(lines, syn, width)
+	}
+
+	// SideBySide hands back pointers into lines; map them to their spans
+	spans := make(map[*diff.Line][]span, len(lines))
+	for i := range lines {
+		spans[&lines[i]] = syn[i]
+	}
+	cell := func(line *diff.Line, old bool) string {
+		if line == nil {
+			return strings.Repeat(" ", side)
+		}
+		num := line.NewNum
+		if old {
+			num = line.OldNum
+		}
+		return diffLineNumStyle.Render(fmt.Sprintf("%4d ", num)) + fitPieces(diffPieces(*line, spans[line]), side-5, true)
+	}
+
+	sep := diffLineNumStyle.Render(" │ ")
+	rows := diff.SideBySide(lines)
+	out := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.Full != nil {
+			out = append(out, fitPieces(diffPieces(*row.Full, nil), width, false))
+			continue
+		}
+		out = append(out, cell(row.Left, true)+sep+cell(row.Right, false))
+	}
+	return out
+}
+
+// diffPieces styles one line's text, highlighting changed words. With
+// syntax spans the tokens keep their colors and changes become backgrounds.
+func diffPieces(line diff.Line, syn []span) []span {
+	segments := line.Segments
+	if segments == nil {
+		segments = []diff.Segment{{Text: line.Text}}
+	}
+	if syn != nil {
+		return layerDiffSyntax(line.Kind, segments, syn)
+	}
+
+	base, word := lipgloss.NewStyle(), lipgloss.NewStyle()
+	switch line.Kind {
+	case diff.Added:
+		base, word = diffAddStyle, diffAddWordStyle
+	case diff.Removed:
+		base, word = diffRemoveStyle, diffRemoveWordStyle
+	case diff.Hunk:
+		base = diffHunkStyle
+	case diff.NoNewline:
+		base = helpStyle
+	case diff.Header:
+		if isDiffFileHeader(line.Text) {
+			base = diffHeaderStyle
+		} else if strings.HasPrefix(line.Text, diffSectionPrefix) {
+			base = sectionHeaderStyle
+		}
+	}
+
+	pieces := make([]span, 0, len(segments))
+	for _, seg := range segments {
+		style := base
+		if seg.Changed {
+			style = word
+		}
+		pieces = append(pieces, span{text: seg.Text, style: style})
+	}
+	return pieces
+}
+
+// layerDiffSyntax cuts the syntax spans at word-diff boundaries. Both cover
+// the same text, so every cut falls between runes.
+func layerDiffSyntax(kind diff.Kind, segments []diff.Segment, syn []span) []span {
+	lineBg, wordBg := lipgloss.TerminalColor(lipgloss.NoColor{}), lipgloss.TerminalColor(lipgloss.NoColor{})
+	switch kind {
+	case diff.Added:
+		lineBg, wordBg = diffAddBg, diffAddWordBg
+	case diff.Removed:
+		lineBg, wordBg = diffRemoveBg, diffRemoveWordBg
+	}
+
+	var pieces []span
+	si, off := 0, 0
+	for _, seg := range segments {
+		bg := lineBg
+		if seg.Changed {
+			bg = wordBg
+		}
+		rest := seg.Text
+		for rest != "" && si < len(syn) {
+			n := min(len(syn[si].text)-off, len(rest))
+			pieces = append(pieces, span{text: rest[:n], style: syn[si].style.Background(bg)})
+			rest = rest[n:]
+			if off += n; off == len(syn[si].text) {
+				si, off = si+1, 0
+			}
+		}
+	}
+	return pieces
+}
+
+// highlightDiff highlights each file's old and new sides as whole texts so
+// multi-line strings and comments stay in sync, and returns spans per line
+// (nil for headers and unknown languages)
+func highlightDiff(lines []diff.Line) [][]span {
+	out := make([][]span, len(lines))
+	path := ""
+	var oldSide, newSide []int
+	flush := func() {
+		for _, side := range [][]int{oldSide, newSide} {
+			texts := make([]string, len(side))
+			for j, idx := range side {
+				texts[j] = lines[idx].Text
+			}
+			for j, spans := range highlightLines(path, texts) {
+				out[side[j]] = spans
+			}
+		}
+		oldSide, newSide = nil, nil
+	}
+
+	for i, line := range lines {
+		switch line.Kind {
+		case diff.Header:
+			if strings.HasPrefix(line.Text, "diff ") {
+				flush()
+				path = diffHeaderPath(line.Text)
+			} else if p, ok := strings.CutPrefix(line.Text, "+++ "); ok && p != "/dev/null" {
+				path = strings.TrimPrefix(p, "b/")
+			} else if p, ok := strings.CutPrefix(line.Text, "--- "); ok && p != "/dev/null" && path == "" {
+				path = strings.TrimPrefix(p, "a/")
+			}
+		case diff.Context:
+			oldSide = append(oldSide, i)
+			newSide = append(newSide, i)
+		case diff.Removed:
+			oldSide = append(oldSide, i)
+		case diff.Added:
+			newSide = append(newSide, i)
+		}
+	}
+	flush()
+	return out
+}
+
+// diffHeaderPath reads the new path from "diff --git a/x b/y" or the path
+// from "diff --cc x"
+func diffHeaderPath(header string) string {
+	if i := strings.LastIndex(header, " b/"); i >= 0 && strings.HasPrefix(header, "diff --git ") {
+		return header[i+3:]
+	}
+	if fields := strings.Fields(header); len(fields) == 3 {
+		return fields[2]
+	}
+	return ""
+}
+
+// fitPieces renders styled pieces within width display cells, ending in
+// an ellipsis when cut and padding when pad is set. Tabs expand to 4 spaces.
+func fitPieces(pieces []span, width int, pad bool) string {
+	var sb strings.Builder
+	used := 0
+	for i, piece := range pieces {
+		if used >= width {
+			break
+		}
+		text := strings.ReplaceAll(piece.text, "\t", "    ")
+		truncated := false
+		if w := runewidth.StringWidth(text); used+w > width {
+			text = runewidth.Truncate(text, width-used, "…")
+			truncated = true
+		} else if used+w == width && i < len(pieces)-1 {
+			// Full but more to come: make room for the ellipsis
+			text = runewidth.Truncate(text, width-used-1, "") + "…"
+			truncated = true
+		}
+		used += runewidth.StringWidth(text)
+
+		sb.WriteString(piece.style.Render(text))
+		if truncated {
+			break
+		}
+	}
+
+	if pad && used < width {
+		sb.WriteString(strings.Repeat(" ", width-used))
+	}
+	return sb.String()
+}
+
+// renderConflictLines lays out predicted conflicts: each file with git's
+// message, then its conflict hunks with the markers highlighted
+func renderConflictLines(conflicts []git.PredictedConflict, width int) []string {
+	var out []string
+	for i, c := range conflicts {
+		if i > 0 {
+			out = append(out, "")
+		}
+		out = append(out, fitPieces([]span{{text: c.Path + " (" + c.Type + ")", style: diffHeaderStyle}}, width, false))
+		if c.Message != "" {
+			out = append(out, fitPieces([]span{{text: c.Message, style: helpStyle}}, width, false))
+		}
+		for j, hunk := range c.Hunks {
+			if j > 0 {
+				out = append(out, helpStyle.Render("…"))
+			}
+			for _, line := range strings.Split(hunk, "\n") {
+				style := lipgloss.NewStyle()
+				for _, marker := range []string{"<<<<<<< ", "||||||| ", "=======", ">>>>>>> "} {
+					if strings.HasPrefix(line, marker) {
+						style = diffHunkStyle
+					}
+				}
+				out = append(out, fitPieces([]span{{text: line, style: style}}, width, false))
+			}
+		}
+	}
+	return out
+}
+
+// diffOptsLabel lists the diff options that differ from git's defaults
+func diffOptsLabel(opts git.DiffOptions) string {
+	var flags []string
+	if opts.IgnoreWhitespace {
+		flags = append(flags, "-w")
+	}
+	if opts.Context > 0 && opts.Context != 3 {
+		flags = append(flags, fmt.Sprintf("-U%d", opts.Context))
+	}
+	switch opts.Renames {
+	case git.RenamesCopies:
+		flags = append(flags, "-C")
+	case git.RenamesOff:
+		flags = append(flags, "--no-renames")
+	}
+	return strings.Join(flags, " ")
+}
+
+func isDiffFileHeader(text string) bool {
+	for _, prefix := range []string{"diff ", "index ", "--- ", "+++ ", "rename ", "similarity ", "new file", "deleted file", "old mode", "new mode"} {
+		if strings.HasPrefix(text, prefix) {
+			return true
+		}
+	}
+	return false
+}
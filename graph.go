@@ -0,0 +1,235 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/LFroesch/gitty/internal/git"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Lane colors cycle by column so parallel branches stay distinguishable
+var graphColors = []lipgloss.Color{"39", "212", "82", "214", "141", "203", "51", "220"}
+
+// graphCell is one column of a graph row: the lane glyph plus the connector
+// drawn to its right
+type graphCell struct {
+	glyph     string
+	connector string
+	color     int
+	connColor int
+}
+
+// graphRow is the precomputed graph prefix for one commit
+type graphRow []graphCell
+
+// graphBuilder assigns commits to lanes from their parent hashes. Lane state
+// carries over between calls so more history can be appended later.
+type graphBuilder struct {
+	lanes []string // hash each column is waiting for, "" when free
+}
+
+func firstFreeLane(lanes []string) int {
+	for i, h := range lanes {
+		if h == "" {
+			return i
+		}
+	}
+	return len(lanes)
+}
+
+func laneIndex(lanes []string, hash string) int {
+	for i, h := range lanes {
+		if h == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// add lays out the next commit (in topological order) and returns its row
+func (g *graphBuilder) add(commit git.Commit) graphRow {
+	lanes := g.lanes
+
+	col := laneIndex(lanes, commit.Hash)
+	if col < 0 {
+		// Branch tip nobody is waiting for yet
+		col = firstFreeLane(lanes)
+		if col == len(lanes) {
+			lanes = append(lanes, "")
+		}
+		lanes[col] = commit.Hash
+	}
+
+	// Other lanes waiting for this commit converge into it
+	var merging []int
+	for i, h := range lanes {
+		if i != col && h == commit.Hash {
+			merging = append(merging, i)
+		}
+	}
+
+	next := make([]string, len(lanes))
+	copy(next, lanes)
+	for _, i := range merging {
+		next[i] = ""
+	}
+	next[col] = ""
+	if len(commit.Parents) > 0 {
+		next[col] = commit.Parents[0]
+	}
+
+	// Extra merge parents join an existing lane or open a new one
+	forks := map[int]bool{} // column -> newly opened
+	for _, parent := range commit.Parents[min(1, len(commit.Parents)):] {
+		if i := laneIndex(next, parent); i >= 0 {
+			if i != col {
+				forks[i] = false
+			}
+			continue
+		}
+		i := firstFreeLane(next)
+		if i == len(next) {
+			next = append(next, "")
+		}
+		next[i] = parent
+		forks[i] = true
+	}
+
+	width := max(len(lanes), len(next))
+	left, right := col, col
+	for _, i := range merging {
+		left, right = min(left, i), max(right, i)
+	}
+	for i := range forks {
+		left, right = min(left, i), max(right, i)
+	}
+
+	row := make(graphRow, width)
+	for x := 0; x < width; x++ {
+		cell := graphCell{glyph: " ", connector: " ", color: x, connColor: x}
+		passing := x < len(lanes) && lanes[x] != "" && x != col && !contains(merging, x)
+
+		switch newLane, isFork := forks[x]; {
+		case x == col:
+			cell.glyph = "●"
+			if len(commit.Parents) > 1 {
+				cell.glyph = "◎"
+			}
+		case contains(merging, x):
+			cell.glyph = "╯"
+			if x < col {
+				cell.glyph = "╰"
+			}
+		case isFork && newLane:
+			cell.glyph = "╮"
+			if x < col {
+				cell.glyph = "╭"
+			}
+		case isFork:
+			cell.glyph = "┤"
+			if x < col {
+				cell.glyph = "├"
+			}
+		case passing && x > left && x < right:
+			cell.glyph = "┼"
+		case passing:
+			cell.glyph = "│"
+		case x > left && x < right:
+			cell.glyph = "─"
+		}
+
+		if x >= left && x < right {
+			cell.connector = "─"
+		}
+		row[x] = cell
+	}
+
+	// Horizontal segments take the color of the lane they lead to
+	for x := left; x < right; x++ {
+		if x < col {
+			row[x].connColor = left
+			if row[x].glyph == "─" {
+				row[x].color = left
+			}
+		} else {
+			row[x].connColor = right
+			if row[x].glyph == "─" {
+				row[x].color = right
+			}
+		}
+	}
+
+	// Drop trailing free lanes so the graph doesn't drift right
+	for len(next) > 0 && next[len(next)-1] == "" {
+		next = next[:len(next)-1]
+	}
+	g.lanes = next
+
+	return row
+}
+
+func contains(cols []int, x int) bool {
+	for _, c := range cols {
+		if c == x {
+			return true
+		}
+	}
+	return false
+}
+
+// buildGraph lays out a whole commit list
+func buildGraph(commits []git.Commit) []graphRow {
+	var g graphBuilder
+	rows := make([]graphRow, len(commits))
+	for i, c := range commits {
+		rows[i] = g.add(c)
+	}
+	return rows
+}
+
+// render draws the row, truncated to maxLanes columns
+func (r graphRow) render(maxLanes int) string {
+	var sb strings.Builder
+	for x, cell := range r {
+		if x >= maxLanes {
+			sb.WriteString(helpStyle.Render("…"))
+			break
+		}
+		style := lipgloss.NewStyle().Foreground(graphColors[cell.color%len(graphColors)])
+		if cell.glyph == "●" || cell.glyph == "◎" {
+			style = style.Bold(true)
+		}
+		sb.WriteString(style.Render(cell.glyph))
+		sb.WriteString(lipgloss.NewStyle().Foreground(graphColors[cell.connColor%len(graphColors)]).Render(cell.connector))
+	}
+	return sb.String()
+}
+
+// renderRefs styles a commit's ref decorations: HEAD, local branches,
+// remote-tracking branches and tags each get their own color
+func renderRefs(refs []string) string {
+	if len(refs) == 0 {
+		return ""
+	}
+	headStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("51")).Bold(true)
+	localStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("82")).Bold(true)
+	remoteStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("203"))
+	tagStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("220"))
+
+	var parts []string
+	for _, ref := range refs {
+		switch {
+		case strings.HasPrefix(ref, "HEAD -> "):
+			parts = append(parts, headStyle.Render("HEAD → ")+localStyle.Render(strings.TrimPrefix(ref, "HEAD -> refs/heads/")))
+		case ref == "HEAD":
+			parts = append(parts, headStyle.Render("HEAD"))
+		case strings.HasPrefix(ref, "tag: "):
+			parts = append(parts, tagStyle.Render("tag:"+strings.TrimPrefix(ref, "tag: refs/tags/")))
+		case strings.HasPrefix(ref, "refs/remotes/"):
+			parts = append(parts, remoteStyle.Render(strings.TrimPrefix(ref, "refs/remotes/")))
+		case strings.HasPrefix(ref, "refs/heads/"):
+			parts = append(parts, localStyle.Render(strings.TrimPrefix(ref, "refs/heads/")))
+		}
+	}
+	return "(" + strings.Join(parts, ", ") + ") "
+}
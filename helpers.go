@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -24,9 +25,24 @@ func (m model) loadGitChanges() tea.Cmd {
 }
 
 func (m model) loadGitStatus() tea.Cmd {
-	return func() tea.Msg {
+	return tea.Batch(func() tea.Msg {
 		status := git.GetStatus(m.repoPath)
 		return gitStatusMsg(status)
+	}, m.loadRebaseState(), m.loadOperation())
+}
+
+// loadOperation checks for a merge, cherry-pick, revert or bisect waiting
+// on the user
+func (m model) loadOperation() tea.Cmd {
+	return func() tea.Msg {
+		return operationMsg(git.GetOperationState(m.repoPath))
+	}
+}
+
+// loadRebaseState checks for a stopped rebase along with every status load
+func (m model) loadRebaseState() tea.Cmd {
+	return func() tea.Msg {
+		return rebaseStateMsg{state: git.GetRebaseState(m.repoPath)}
 	}
 }
 
@@ -46,10 +62,7 @@ func (m model) loadRecentCommits() tea.Cmd {
 }
 
 func (m model) loadCommitHistory() tea.Cmd {
-	return func() tea.Msg {
-		commits := git.GetCommitLog(m.repoPath, 20)
-		return commitsMsg(commits)
-	}
+	return m.loadCommitPage("history", git.LogQuery{}, 0)
 }
 
 func (m model) loadConflicts() tea.Cmd {
@@ -63,25 +76,80 @@ func (m model) loadConflicts() tea.Cmd {
 	}
 }
 
-func (m model) loadFileDiff(filePath string) tea.Cmd {
+// loadFileDiff loads every side of change with the current diff options and
+// remembers it so toggling an option can reload it
+func (m *model) loadFileDiff(change git.Change) tea.Cmd {
+	if change.File != m.diffChange.File {
+		m.diffShowLarge = false
+	}
+	m.diffChange = change
+	repoPath, opts := m.repoPath, m.diffOpts
 	return func() tea.Msg {
-		staged := git.IsFileStaged(m.repoPath, filePath)
-		diff := git.GetFileDiff(m.repoPath, filePath, staged)
-		return diffMsg(diff)
+		return diffMsg(formatChangeDiff(git.GetChangeDiff(repoPath, change, opts)))
+	}
+}
+
+// reloadFileDiff re-runs the shown diff after a diff option changed
+func (m *model) reloadFileDiff() tea.Cmd {
+	if m.diffChange.File == "" {
+		return nil
+	}
+	return m.loadFileDiff(m.diffChange)
+}
+
+// formatChangeDiff joins the sides of a change, titling them when a
+// partially staged file has both
+func formatChangeDiff(d git.ChangeDiff) string {
+	if d.Staged == "" || d.Unstaged == "" {
+		return d.Staged + d.Unstaged + d.Untracked
+	}
+	return diffSectionPrefix + "Staged\n" + d.Staged + diffSectionPrefix + "Unstaged\n" + d.Unstaged
+}
+
+// Diffs past either limit wait for confirmation before rendering, since
+// parsing, word diffs and highlighting would stall the UI
+const (
+	maxDiffBytes = 512 * 1024
+	maxDiffLines = 10000
+)
+
+func isLargeDiff(content string) bool {
+	return len(content) > maxDiffBytes || strings.Count(content, "\n") > maxDiffLines
+}
+
+// diffContextSteps are the -U values "+" and "-" step through
+var diffContextSteps = []int{1, 2, 3, 5, 10, 20, 50, 100}
+
+// stepDiffContext moves the context size one step up (dir 1) or down (-1)
+func (m *model) stepDiffContext(dir int) {
+	current := m.diffOpts.Context
+	if current == 0 {
+		current = 3
 	}
+	i := 0
+	for i < len(diffContextSteps)-1 && diffContextSteps[i] < current {
+		i++
+	}
+	i = max(0, min(i+dir, len(diffContextSteps)-1))
+	m.diffOpts.Context = diffContextSteps[i]
 }
 
 func (m model) loadRebaseCommits() tea.Cmd {
 	return func() tea.Msg {
 		countStr := strings.TrimSpace(m.rebaseInput.Value())
 		count, err := strconv.Atoi(countStr)
-		if err != nil || count < 1 || count > 50 {
-			return statusMsg{message: "Invalid count (1-50)"}
+		if err != nil || count < 1 {
+			return statusMsg{message: "Invalid count"}
+		}
+
+		// The rebase base is HEAD~count, so one more commit must exist
+		commits := git.GetCommitPage(m.repoPath, git.LogQuery{}, 0, count+1)
+		if len(commits) <= count {
+			return statusMsg{message: fmt.Sprintf("Only %d commits can be rebased", max(0, len(commits)-1))}
 		}
 
-		commits := git.GetCommitLog(m.repoPath, count)
 		var rebaseCommits []git.RebaseCommit
-		for _, c := range commits {
+		for _, c := range commits[:count] {
 			rebaseCommits = append(rebaseCommits, git.RebaseCommit{
 				Hash:    c.Hash,
 				Message: c.Message,
@@ -92,19 +160,129 @@ func (m model) loadRebaseCommits() tea.Cmd {
 	}
 }
 
-// Staging operations
+// History paging
+
+const (
+	historyPageSize = 100
+	historyPrefetch = 20 // load the next page when the cursor is this close to the end
+)
+
+// commitPager tracks how far a lazily loaded history view has been read
+type commitPager struct {
+	query   git.LogQuery
+	loading bool
+	done    bool
+}
 
-func (m model) toggleStaging(filePath string) tea.Cmd {
+// loadCommitPage fetches one page of history. The first page pins the query
+// to the current HEAD hash so later pages line up even if new commits land.
+func (m model) loadCommitPage(target string, query git.LogQuery, skip int) tea.Cmd {
 	return func() tea.Msg {
-		isStaged := git.IsFileStaged(m.repoPath, filePath)
+		if query.Rev == "" {
+			query.Rev = git.ResolveRev(m.repoPath, "HEAD")
+		}
+		var commits []git.Commit
+		if query.Rev != "" {
+			commits = git.GetCommitPage(m.repoPath, query, skip, historyPageSize)
+		}
+		return commitPageMsg{target: target, query: query, skip: skip, commits: commits}
+	}
+}
 
+// loadMoreCommits requests the next page once the cursor nears the end of
+// what's loaded
+func (m *model) loadMoreCommits(target string, cursor int) tea.Cmd {
+	pager, loaded := &m.commitsPager, len(m.commits)
+	switch target {
+	case "log":
+		pager, loaded = &m.logPager, len(m.logCommits)
+	case "file":
+		pager, loaded = &m.fileLogPager, len(m.fileLogRevs)
+	}
+	if pager.loading || pager.done || cursor < loaded-historyPrefetch {
+		return nil
+	}
+	pager.loading = true
+	if target == "file" {
+		return m.loadFileHistory(pager.query, loaded)
+	}
+	return m.loadCommitPage(target, pager.query, loaded)
+}
+
+// File history operations
+
+// openFileHistory switches the workspace to the history of path
+func (m *model) openFileHistory(path string) tea.Cmd {
+	m.fileLogReturn = m.viewMode
+	m.viewMode = "filelog"
+	m.fileLogPath = path
+	m.fileLogRevs = nil
+	m.fileLogPager = commitPager{}
+	m.fileLogCursor = 0
+	m.fileLogOffset = 0
+	m.fileLogShow = ""
+	return m.loadFileHistory(git.LogQuery{Paths: []string{path}}, 0)
+}
+
+func (m model) loadFileHistory(query git.LogQuery, skip int) tea.Cmd {
+	return func() tea.Msg {
+		if query.Rev == "" {
+			query.Rev = git.ResolveRev(m.repoPath, "HEAD")
+		}
+		var revisions []git.FileRevision
+		if query.Rev != "" {
+			revisions = git.GetFileHistory(m.repoPath, query.Rev, query.Paths[0], skip, historyPageSize)
+		}
+		return fileHistoryMsg{query: query, skip: skip, revisions: revisions}
+	}
+}
+
+// loadFileRevision loads one revision's diff for the file, or the whole file
+// as of that revision
+func (m model) loadFileRevision(rev git.FileRevision, show string) tea.Cmd {
+	return func() tea.Msg {
+		if show == "content" {
+			content, err := git.GetFileAtRevision(m.repoPath, rev.Hash, rev.Path)
+			if err != nil {
+				return statusMsg{message: fmt.Sprintf("Open file failed: %v", err)}
+			}
+			syntax := highlightLines(rev.Path, strings.Split(strings.TrimRight(content, "\n"), "\n"))
+			return fileRevisionMsg{show: show, text: content, syntax: syntax}
+		}
+		return fileRevisionMsg{show: show, text: git.GetFileRevisionDiff(m.repoPath, rev.Hash, rev.Path)}
+	}
+}
+
+// Filesystem watching
+
+// waitForRepoChange blocks until the watcher reports a change. It must be
+// re-issued after each repoChangedMsg to keep listening.
+func (m model) waitForRepoChange() tea.Cmd {
+	if m.watcher == nil {
+		return nil
+	}
+	events := m.watcher.Events()
+	return func() tea.Msg {
+		ev, ok := <-events
+		if !ok {
+			return nil
+		}
+		return repoChangedMsg(ev)
+	}
+}
+
+// Staging operations
+
+func (m model) toggleStaging(change git.Change) tea.Cmd {
+	return func() tea.Msg {
 		var gitCmd []string
 		var action string
-		if isStaged {
-			gitCmd = []string{"reset", "HEAD", filePath}
+		if change.IsStaged() {
+			// Both sides of a rename, so the old path comes back too
+			gitCmd = append([]string{"reset", "HEAD", "--"}, change.Paths()...)
 			action = "unstaged"
 		} else {
-			gitCmd = []string{"add", filePath}
+			gitCmd = []string{"add", "--", change.File}
 			action = "staged"
 		}
 
@@ -117,7 +295,7 @@ func (m model) toggleStaging(filePath string) tea.Cmd {
 			m.loadGitChanges(),
 			m.loadGitStatus(),
 			func() tea.Msg {
-				return statusMsg{message: fmt.Sprintf("%s: %s", cases.Title(language.English).String(action), filePath)}
+				return statusMsg{message: fmt.Sprintf("%s: %s", cases.Title(language.English).String(action), change.File)}
 			},
 		)()
 	}
@@ -164,9 +342,14 @@ func (m model) gitReset() tea.Cmd {
 
 func (m model) gitResetLastCommit() tea.Cmd {
 	return func() tea.Msg {
+		checkpoint, err := git.Checkpoint(m.repoPath, "reset last commit", nil)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Reset cancelled: %v", err)}
+		}
 		// Mixed reset: undo last commit, keep changes in working directory (unstaged)
 		output, err := git.Execute(m.repoPath, "reset", "HEAD~1")
 		if err != nil {
+			git.DropCheckpoint(m.repoPath, checkpoint)
 			return statusMsg{message: fmt.Sprintf("Reset failed: %v - %s", err, string(output))}
 		}
 
@@ -181,10 +364,16 @@ func (m model) gitResetLastCommit() tea.Cmd {
 	}
 }
 
-func (m model) discardChanges(filePath string) tea.Cmd {
+func (m model) discardChanges(change git.Change) tea.Cmd {
+	filePath := change.File
 	return func() tea.Msg {
+		checkpoint, err := git.Checkpoint(m.repoPath, "discard "+filePath, nil)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Discard cancelled: %v", err)}
+		}
 		output, err := git.Execute(m.repoPath, "checkout", "--", filePath)
 		if err != nil {
+			git.DropCheckpoint(m.repoPath, checkpoint)
 			return statusMsg{message: fmt.Sprintf("Failed to discard changes: %v - %s", err, string(output))}
 		}
 
@@ -192,7 +381,7 @@ func (m model) discardChanges(filePath string) tea.Cmd {
 			m.loadGitChanges(),
 			m.loadGitStatus(),
 			func() tea.Msg {
-				return statusMsg{message: fmt.Sprintf("Discarded changes: %s", filePath)}
+				return statusMsg{message: fmt.Sprintf("Discarded changes: %s (U to undo)", filePath)}
 			},
 		)()
 	}
@@ -231,41 +420,55 @@ func (m model) generateCommitSuggestions() tea.Cmd {
 		if len(changes) == 0 {
 			return commitSuggestionsMsg(nil)
 		}
+		return commitSuggestionsMsg(buildCommitSuggestions(changes))
+	}
+}
 
-		var suggestions []CommitSuggestion
-		typeCount := make(map[string]int)
-
-		for _, change := range changes {
-			changeType := categorizeChange(change)
-			typeCount[changeType]++
-		}
-
-		// Generate suggestions based on change patterns
-		for changeType, count := range typeCount {
-			var msg string
-			switch changeType {
-			case "feat":
-				msg = fmt.Sprintf("feat: add new feature (%d files)", count)
-			case "fix":
-				msg = fmt.Sprintf("fix: resolve issue (%d files)", count)
-			case "docs":
-				msg = fmt.Sprintf("docs: update documentation (%d files)", count)
-			case "style":
-				msg = fmt.Sprintf("style: improve formatting (%d files)", count)
-			case "refactor":
-				msg = fmt.Sprintf("refactor: improve code structure (%d files)", count)
-			case "test":
-				msg = fmt.Sprintf("test: add/update tests (%d files)", count)
-			case "chore":
-				msg = fmt.Sprintf("chore: update build/config (%d files)", count)
-			default:
-				msg = fmt.Sprintf("chore: update files (%d files)", count)
-			}
-			suggestions = append(suggestions, CommitSuggestion{Message: msg, Type: changeType})
-		}
+// buildCommitSuggestions groups changes by conventional commit type and
+// ranks the resulting messages by how many files each type covers
+func buildCommitSuggestions(changes []git.Change) []CommitSuggestion {
+	var suggestions []CommitSuggestion
+	typeCount := make(map[string]int)
 
-		return commitSuggestionsMsg(suggestions)
+	for _, change := range changes {
+		changeType := categorizeChange(change)
+		typeCount[changeType]++
 	}
+
+	// Generate suggestions based on change patterns
+	for changeType, count := range typeCount {
+		var msg string
+		switch changeType {
+		case "feat":
+			msg = fmt.Sprintf("feat: add new feature (%d files)", count)
+		case "fix":
+			msg = fmt.Sprintf("fix: resolve issue (%d files)", count)
+		case "docs":
+			msg = fmt.Sprintf("docs: update documentation (%d files)", count)
+		case "style":
+			msg = fmt.Sprintf("style: improve formatting (%d files)", count)
+		case "refactor":
+			msg = fmt.Sprintf("refactor: improve code structure (%d files)", count)
+		case "test":
+			msg = fmt.Sprintf("test: add/update tests (%d files)", count)
+		case "chore":
+			msg = fmt.Sprintf("chore: update build/config (%d files)", count)
+		default:
+			msg = fmt.Sprintf("chore: update files (%d files)", count)
+		}
+		suggestions = append(suggestions, CommitSuggestion{Message: msg, Type: changeType})
+	}
+
+	// Most files first; type name breaks ties so the order is stable
+	sort.Slice(suggestions, func(i, j int) bool {
+		ci, cj := typeCount[suggestions[i].Type], typeCount[suggestions[j].Type]
+		if ci != cj {
+			return ci > cj
+		}
+		return suggestions[i].Type < suggestions[j].Type
+	})
+
+	return suggestions
 }
 
 func categorizeChange(change git.Change) string {
@@ -346,15 +549,20 @@ func (m model) createBranch(branchName string) tea.Cmd {
 
 func (m model) deleteBranch(branchName string) tea.Cmd {
 	return func() tea.Msg {
+		checkpoint, err := git.Checkpoint(m.repoPath, "delete branch "+branchName, nil)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Delete cancelled: %v", err)}
+		}
 		output, err := git.Execute(m.repoPath, "branch", "-d", branchName)
 		if err != nil {
+			git.DropCheckpoint(m.repoPath, checkpoint)
 			return statusMsg{message: fmt.Sprintf("Failed to delete branch: %s", string(output))}
 		}
 
 		return tea.Batch(
 			m.loadBranches(),
 			func() tea.Msg {
-				return statusMsg{message: fmt.Sprintf("Deleted branch '%s'", branchName)}
+				return statusMsg{message: fmt.Sprintf("Deleted branch '%s' (U to undo)", branchName)}
 			},
 		)()
 	}
@@ -363,11 +571,35 @@ func (m model) deleteBranch(branchName string) tea.Cmd {
 func (m model) compareBranch(targetBranch string) tea.Cmd {
 	return func() tea.Msg {
 		currentBranch := git.GetBranchName(m.repoPath)
-		comparison := git.GetBranchComparison(m.repoPath, currentBranch, targetBranch)
+		comparison := git.GetBranchComparison(m.repoPath, currentBranch, targetBranch, true)
 		return comparisonMsg(comparison)
 	}
 }
 
+// compareRefs compares any two refs, checking both exist first
+func (m model) compareRefs(target, source string, threeDot bool) tea.Cmd {
+	return func() tea.Msg {
+		for _, ref := range []string{target, source} {
+			if git.ResolveRev(m.repoPath, ref) == "" {
+				return statusMsg{message: fmt.Sprintf("Compare failed: unknown ref %q", ref)}
+			}
+		}
+		return comparisonMsg(git.GetBranchComparison(m.repoPath, source, target, threeDot))
+	}
+}
+
+// loadCompareDiff loads one file's diff within the open comparison
+func (m model) loadCompareDiff(file git.FileStat) tea.Cmd {
+	comparison, opts := *m.branchComparison, m.diffOpts
+	paths := []string{file.Path}
+	if file.OrigPath != "" {
+		paths = []string{file.OrigPath, file.Path}
+	}
+	return func() tea.Msg {
+		return compareDiffMsg{path: file.Path, text: git.GetComparisonFileDiff(m.repoPath, comparison, opts, paths...)}
+	}
+}
+
 // Remote operations
 
 func (m model) pushChanges() tea.Cmd {
@@ -418,11 +650,31 @@ func (m model) fetchChanges() tea.Cmd {
 
 // Undo operations
 
-func (m model) undoToCommit(hash string) tea.Cmd {
+// loadResetPreview works out what resetting to the selected commit would do
+func (m *model) loadResetPreview() tea.Cmd {
+	m.resetPreview = nil
+	if m.undoCursor >= len(m.commits) {
+		return nil
+	}
+	hash := m.commits[m.undoCursor].Hash
 	return func() tea.Msg {
-		output, err := git.Execute(m.repoPath, "reset", "--soft", hash)
+		preview, err := git.PreviewReset(m.repoPath, hash)
 		if err != nil {
-			return statusMsg{message: fmt.Sprintf("Undo failed: %s", string(output))}
+			return statusMsg{message: fmt.Sprintf("Preview failed: %v", err)}
+		}
+		return resetPreviewMsg(preview)
+	}
+}
+
+func (m model) undoToCommit(hash string, mode git.ResetMode) tea.Cmd {
+	return func() tea.Msg {
+		checkpoint, err := git.Checkpoint(m.repoPath, fmt.Sprintf("%s reset to %s", mode, hash), nil)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Undo cancelled: %v", err)}
+		}
+		if err := git.Reset(m.repoPath, hash, mode); err != nil {
+			git.DropCheckpoint(m.repoPath, checkpoint)
+			return statusMsg{message: fmt.Sprintf("Undo failed: %v", err)}
 		}
 
 		return tea.Batch(
@@ -430,7 +682,28 @@ func (m model) undoToCommit(hash string) tea.Cmd {
 			m.loadGitStatus(),
 			m.loadCommitHistory(),
 			func() tea.Msg {
-				return statusMsg{message: fmt.Sprintf("Reset to commit %s", hash)}
+				return statusMsg{message: fmt.Sprintf("Reset to commit %s (%s, U to undo)", hash, mode)}
+			},
+		)()
+	}
+}
+
+// undoLastAction restores the state saved before the last destructive action
+func (m model) undoLastAction() tea.Cmd {
+	return func() tea.Msg {
+		entry, err := git.UndoLast(m.repoPath)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Undo failed: %v", err)}
+		}
+
+		return tea.Batch(
+			m.loadGitChanges(),
+			m.loadGitStatus(),
+			m.loadCommitHistory(),
+			m.loadBranches(),
+			m.loadStashList(),
+			func() tea.Msg {
+				return statusMsg{message: fmt.Sprintf("Undid: %s (U again to redo)", entry.Action)}
 			},
 		)()
 	}
@@ -438,28 +711,130 @@ func (m model) undoToCommit(hash string) tea.Cmd {
 
 // Rebase operations
 
+// predictRebase dry-runs the rebase plan to find conflicts before executing
+func (m model) predictRebase() tea.Cmd {
+	commits := append([]git.RebaseCommit(nil), m.rebaseCommits...)
+	return func() tea.Msg {
+		return rebasePredictionMsg(git.PredictRebase(m.repoPath, commits, m.rebaseOpts))
+	}
+}
+
 func (m model) executeRebase() tea.Cmd {
 	return func() tea.Msg {
 		if len(m.rebaseCommits) == 0 {
 			return statusMsg{message: "No commits to rebase"}
 		}
 
-		err := git.ExecuteRebase(m.repoPath, m.rebaseCommits)
+		checkpoint, err := git.Checkpoint(m.repoPath, fmt.Sprintf("rebase %d commits", len(m.rebaseCommits)), nil)
 		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Rebase cancelled: %v", err)}
+		}
+		// A rebase that stops on a conflict fails but stays in progress
+		err = git.ExecuteRebase(m.repoPath, m.rebaseCommits, m.rebaseOpts)
+		if err != nil && !git.IsRebaseInProgress(m.repoPath) {
+			git.DropCheckpoint(m.repoPath, checkpoint)
 			return statusMsg{message: fmt.Sprintf("Rebase failed: %v", err)}
 		}
 
+		message := "Rebase completed successfully"
+		if state := git.GetRebaseState(m.repoPath); state != nil {
+			message = rebaseStoppedMessage(state)
+		}
 		return tea.Batch(
 			m.loadGitChanges(),
 			m.loadGitStatus(),
 			m.loadCommitHistory(),
 			func() tea.Msg {
-				return statusMsg{message: "Rebase completed successfully"}
+				return statusMsg{message: message}
 			},
 		)()
 	}
 }
 
+// rebaseStoppedMessage says why a rebase is waiting
+func rebaseStoppedMessage(state *git.RebaseState) string {
+	if len(state.Conflicts) > 0 {
+		return fmt.Sprintf("Rebase stopped on conflicts in %d files (step %d/%d): resolve them, then c to continue",
+			len(state.Conflicts), state.Step, state.Total)
+	}
+	return fmt.Sprintf("Rebase stopped at step %d/%d: amend if needed, then c to continue", state.Step, state.Total)
+}
+
+// operationNames label operations in messages and the banner
+var operationNames = map[git.Operation]string{
+	git.OpRebase:     "Rebase",
+	git.OpMerge:      "Merge",
+	git.OpCherryPick: "Cherry-pick",
+	git.OpRevert:     "Revert",
+	git.OpBisect:     "Bisect",
+}
+
+// operationStoppedMessage says what a merge, cherry-pick or revert is
+// waiting for
+func operationStoppedMessage(state git.OperationState) string {
+	name := operationNames[state.Op]
+	if state.Head != "" {
+		name += " of " + state.Head
+	}
+	if len(state.Conflicts) > 0 {
+		return fmt.Sprintf("%s stopped on conflicts in %d files: resolve them, then O to continue", name, len(state.Conflicts))
+	}
+	return name + " in progress: O to continue or abort"
+}
+
+// controlOperation runs continue, skip or abort on the operation in
+// progress
+func (m model) controlOperation(op git.Operation, action string) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		switch action {
+		case "continue":
+			err = git.ContinueOperation(m.repoPath, op)
+		case "skip":
+			err = git.SkipOperation(m.repoPath, op)
+		case "abort":
+			err = git.AbortOperation(m.repoPath, op)
+		}
+
+		name := operationNames[op]
+		message := name + " completed successfully"
+		state := git.GetOperationState(m.repoPath)
+		switch {
+		case action == "abort" && err == nil:
+			message = name + " aborted"
+		case op == git.OpBisect && err == nil:
+			message = "Skipped; test the next commit"
+		case state.Op == op && op == git.OpRebase && (err == nil || len(state.Conflicts) > 0):
+			// Stopped again further on
+			message = rebaseStoppedMessage(git.GetRebaseState(m.repoPath))
+		case state.Op == op && (err == nil || len(state.Conflicts) > 0):
+			message = operationStoppedMessage(state)
+		case err != nil:
+			message = fmt.Sprintf("%s %s failed: %v", name, action, err)
+		}
+		return tea.Batch(
+			m.loadGitChanges(),
+			m.loadGitStatus(),
+			m.loadRecentCommits(),
+			func() tea.Msg {
+				return statusMsg{message: message}
+			},
+		)()
+	}
+}
+
+// editRebaseTodo hands the terminal to the user's editor for the todo
+func (m model) editRebaseTodo() tea.Cmd {
+	return tea.ExecProcess(git.EditRebaseTodoCommand(m.repoPath), func(err error) tea.Msg {
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Edit todo failed: %v", err)}
+		}
+		return tea.Batch(m.loadRebaseState(), func() tea.Msg {
+			return statusMsg{message: "Rebase todo updated"}
+		})()
+	})
+}
+
 // Stash operations
 
 func (m model) loadStashList() tea.Cmd {
@@ -476,19 +851,98 @@ func (m model) loadStashDiff(index int) tea.Cmd {
 	}
 }
 
-func (m model) stashPush(message string) tea.Cmd {
+func (m model) stashPush(opts git.StashOptions) tea.Cmd {
 	return func() tea.Msg {
-		err := git.StashPush(m.repoPath, message)
+		err := git.StashPush(m.repoPath, opts)
 		if err != nil {
 			return statusMsg{message: fmt.Sprintf("Stash failed: %v", err)}
 		}
 
+		message := "Changes stashed"
+		if len(opts.Paths) > 0 {
+			message = fmt.Sprintf("Stashed %d files", len(opts.Paths))
+		}
+		return tea.Batch(
+			m.loadStashList(),
+			m.loadGitChanges(),
+			m.loadGitStatus(),
+			func() tea.Msg {
+				return statusMsg{message: message}
+			},
+		)()
+	}
+}
+
+// stashMarked stashes the files marked in the workspace, or the selected one
+func (m model) stashMarked() tea.Cmd {
+	opts := m.stashOpts
+	for _, change := range m.changes {
+		if !m.stashMarks[change.File] {
+			continue
+		}
+		opts.Paths = append(opts.Paths, change.Paths()...)
+		// Pathspecs only match untracked files with -u
+		if change.Kind == git.ChangeUntracked {
+			opts.IncludeUntracked = true
+			opts.Staged = false
+		}
+	}
+	if len(opts.Paths) == 0 && m.fileCursor < len(m.changes) {
+		change := m.changes[m.fileCursor]
+		opts.Paths = change.Paths()
+		if change.Kind == git.ChangeUntracked {
+			opts.IncludeUntracked = true
+			opts.Staged = false
+		}
+	}
+	if len(opts.Paths) == 0 {
+		return nil
+	}
+	return m.stashPush(opts)
+}
+
+func (m model) stashRename(index int, message string) tea.Cmd {
+	return func() tea.Msg {
+		checkpoint, err := git.Checkpoint(m.repoPath, fmt.Sprintf("rename stash@{%d}", index), nil)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Rename cancelled: %v", err)}
+		}
+		if err := git.RenameStash(m.repoPath, index, message); err != nil {
+			// Failing between the drops and the stores loses stashes
+			if dropped, _ := git.DropCheckpoint(m.repoPath, checkpoint); !dropped {
+				return statusMsg{message: fmt.Sprintf("Rename failed: %v (U to undo)", err)}
+			}
+			return statusMsg{message: fmt.Sprintf("Rename failed: %v", err)}
+		}
+
+		return tea.Batch(
+			m.loadStashList(),
+			func() tea.Msg {
+				return statusMsg{message: fmt.Sprintf("Renamed stash@{%d}", index)}
+			},
+		)()
+	}
+}
+
+// stashToBranch checks out a new branch where the stash was made and pops it there
+func (m model) stashToBranch(index int, name string) tea.Cmd {
+	return func() tea.Msg {
+		checkpoint, err := git.Checkpoint(m.repoPath, fmt.Sprintf("branch %s from stash@{%d}", name, index), nil)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Branch cancelled: %v", err)}
+		}
+		if err := git.StashBranch(m.repoPath, name, index); err != nil {
+			git.DropCheckpoint(m.repoPath, checkpoint)
+			return statusMsg{message: fmt.Sprintf("Branch from stash failed: %v", err)}
+		}
+
 		return tea.Batch(
 			m.loadStashList(),
 			m.loadGitChanges(),
 			m.loadGitStatus(),
+			m.loadBranches(),
 			func() tea.Msg {
-				return statusMsg{message: "Changes stashed"}
+				return statusMsg{message: fmt.Sprintf("Switched to new branch '%s' with the stash applied", name)}
 			},
 		)()
 	}
@@ -531,15 +985,20 @@ func (m model) stashApply(index int) tea.Cmd {
 
 func (m model) stashDrop(index int) tea.Cmd {
 	return func() tea.Msg {
-		err := git.StashDrop(m.repoPath, index)
+		checkpoint, err := git.Checkpoint(m.repoPath, fmt.Sprintf("drop stash@{%d}", index), nil)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Stash drop cancelled: %v", err)}
+		}
+		err = git.StashDrop(m.repoPath, index)
 		if err != nil {
+			git.DropCheckpoint(m.repoPath, checkpoint)
 			return statusMsg{message: fmt.Sprintf("Stash drop failed: %v", err)}
 		}
 
 		return tea.Batch(
 			m.loadStashList(),
 			func() tea.Msg {
-				return statusMsg{message: "Stash dropped"}
+				return statusMsg{message: "Stash dropped (U to undo)"}
 			},
 		)()
 	}
@@ -684,10 +1143,40 @@ func (m model) removeSelectedHook() tea.Cmd {
 
 // Log viewer operations
 
-func (m model) loadLogCommits(search string) tea.Cmd {
+func (m model) loadLogCommits() tea.Cmd {
+	return m.loadCommitPage("log", m.logQuery(), 0)
+}
+
+// logQuery combines the message search with the filter bar
+func (m model) logQuery() git.LogQuery {
+	query := m.logFilter
+	query.Search = m.logSearch
+	return query
+}
+
+// applyLogFilter parses a filter bar string and reloads the log from the top
+func (m *model) applyLogFilter(input, preset string) tea.Cmd {
+	filter, err := git.ParseLogFilter(input)
+	if err != nil {
+		return func() tea.Msg { return statusMsg{message: fmt.Sprintf("Invalid filter: %v", err)} }
+	}
+	m.logFilter = filter
+	m.logPreset = preset
+	m.logCursor = 0
+	m.logOffset = 0
+	return m.loadLogCommits()
+}
+
+// saveLogPreset stores the current filter under name in the config file
+func (m *model) saveLogPreset(name string) tea.Cmd {
+	m.config.SetLogPreset(name, m.logFilter.String())
+	m.logPreset = name
+	cfg := m.config
 	return func() tea.Msg {
-		commits := git.GetCommitLog2(m.repoPath, 50, search)
-		return logCommitsMsg(commits)
+		if err := cfg.Save(); err != nil {
+			return statusMsg{message: fmt.Sprintf("Save preset failed: %v", err)}
+		}
+		return statusMsg{message: "Saved log preset " + name}
 	}
 }
 
@@ -705,36 +1194,285 @@ func (m model) loadLogDetail(hash string) tea.Cmd {
 // Blame operations
 
 func (m model) loadBlame(filePath string) tea.Cmd {
+	return m.reloadBlame(filePath, 0)
+}
+
+// reloadBlame blames filePath with the current options and selects cursor
+func (m model) reloadBlame(filePath string, cursor int) tea.Cmd {
+	opts := m.blameOpts
+	return func() tea.Msg {
+		lines, err := git.GetBlame(m.repoPath, filePath, opts)
+		if err != nil {
+			return statusMsg{message: err.Error()}
+		}
+		contents := make([]string, len(lines))
+		for i, line := range lines {
+			contents[i] = line.Content
+		}
+		return blameMsg{
+			lines:      lines,
+			syntax:     highlightLines(filePath, contents),
+			ignoreRevs: git.BlameIgnoreRevsFile(m.repoPath),
+			cursor:     cursor,
+		}
+	}
+}
+
+// loadBlameMessage fetches the full message of the commit under the cursor
+func (m model) loadBlameMessage() tea.Cmd {
+	if m.blameCursor >= len(m.blameLines) {
+		return nil
+	}
+	line := m.blameLines[m.blameCursor]
+	if line.IsUncommitted() {
+		return nil
+	}
+	if _, ok := m.blameMessages[line.FullHash]; ok {
+		return nil
+	}
 	return func() tea.Msg {
-		lines := git.GetBlame(m.repoPath, filePath)
-		return blameMsg(lines)
+		return blameMessageMsg{hash: line.FullHash, message: git.GetCommitMessage(m.repoPath, line.FullHash)}
+	}
+}
+
+// blameParent re-blames the cursor line's file at the parent of the commit
+// that last touched it, to look past reformats and moves
+func (m *model) blameParent() tea.Cmd {
+	if m.blameCursor >= len(m.blameLines) {
+		return nil
+	}
+	line := m.blameLines[m.blameCursor]
+	if line.IsUncommitted() {
+		return func() tea.Msg { return statusMsg{message: "Line is not committed yet"} }
+	}
+	if line.PrevHash == "" {
+		return func() tea.Msg { return statusMsg{message: "No earlier revision: " + line.Hash + " added this line"} }
+	}
+
+	m.blameStack = append(m.blameStack, blameFrame{rev: m.blameOpts.Rev, file: m.blameFile, cursor: m.blameCursor})
+	m.blameOpts.Rev = line.PrevHash
+	m.blameFile = line.PrevPath
+	return m.reloadBlame(line.PrevPath, line.OrigLine-1)
+}
+
+// openBlameCommit shows the cursor line's commit in the log detail view
+func (m *model) openBlameCommit() tea.Cmd {
+	if m.blameCursor >= len(m.blameLines) {
+		return nil
+	}
+	line := m.blameLines[m.blameCursor]
+	if line.IsUncommitted() {
+		return func() tea.Msg { return statusMsg{message: "Line is not committed yet"} }
+	}
+	m.tab = "tools"
+	m.toolMode = "log"
+	m.logDetailReturn = "blame"
+	m.scrollOffset = 0
+	return m.loadLogDetail(line.FullHash)
+}
+
+// Bisect operations
+
+func (m model) loadBisectState() tea.Cmd {
+	return func() tea.Msg {
+		return bisectStateMsg{state: git.GetBisectState(m.repoPath)}
+	}
+}
+
+// bisectResult reloads after a bisect step and reports git's summary line
+func (m model) bisectResult(output string, err error, failed string) tea.Msg {
+	if err != nil {
+		return statusMsg{message: fmt.Sprintf("%s: %v", failed, err)}
+	}
+	message, _, _ := strings.Cut(output, "\n")
+	if strings.Contains(output, "is the first bad commit") {
+		message = "Found the first bad commit"
+	}
+	return tea.Batch(
+		m.loadBisectState(),
+		m.loadGitChanges(),
+		m.loadGitStatus(),
+		func() tea.Msg {
+			return statusMsg{message: message}
+		},
+	)()
+}
+
+func (m model) startBisect(bad, good string) tea.Cmd {
+	return func() tea.Msg {
+		output, err := git.StartBisect(m.repoPath, bad, good)
+		return m.bisectResult(output, err, "Bisect start failed")
+	}
+}
+
+// markBisect marks the tested commit, or the given commits, good, bad or skip
+func (m model) markBisect(mark string, revs ...string) tea.Cmd {
+	return func() tea.Msg {
+		output, err := git.MarkBisect(m.repoPath, mark, revs...)
+		return m.bisectResult(output, err, "Bisect "+mark+" failed")
+	}
+}
+
+func (m model) resetBisect() tea.Cmd {
+	return func() tea.Msg {
+		if err := git.ResetBisect(m.repoPath); err != nil {
+			return statusMsg{message: fmt.Sprintf("Bisect reset failed: %v", err)}
+		}
+		return tea.Batch(
+			m.loadBisectState(),
+			m.loadGitChanges(),
+			m.loadGitStatus(),
+			func() tea.Msg {
+				return statusMsg{message: "Bisect ended, back on the original branch"}
+			},
+		)()
+	}
+}
+
+func (m model) startBisectRun(command string) tea.Cmd {
+	return func() tea.Msg {
+		run, err := git.StartBisectRun(m.repoPath, command)
+		if err != nil {
+			return statusMsg{message: err.Error()}
+		}
+		return bisectRunMsg{run: run}
+	}
+}
+
+// waitForBisectOutput delivers the run's output one line at a time
+func waitForBisectOutput(run *git.BisectRun) tea.Cmd {
+	return func() tea.Msg {
+		line, ok := <-run.Lines
+		if !ok {
+			return bisectRunDoneMsg{err: run.Err()}
+		}
+		return bisectOutputMsg(line)
+	}
+}
+
+// Reflog operations
+
+// reflogPageSize is how many more reflog entries load at the end of the list
+const reflogPageSize = 200
+
+func (m model) loadReflog(ref string, count int) tea.Cmd {
+	return func() tea.Msg {
+		return reflogMsg{ref: ref, entries: git.GetReflog(m.repoPath, ref, count)}
+	}
+}
+
+// branchFromReflog creates a branch at a reflog entry, leaving HEAD alone
+func (m model) branchFromReflog(name string, entry git.ReflogEntry) tea.Cmd {
+	return func() tea.Msg {
+		output, err := git.Execute(m.repoPath, "branch", name, entry.Hash)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Failed to create branch: %s", strings.TrimSpace(string(output)))}
+		}
+
+		return tea.Batch(
+			m.loadBranches(),
+			m.loadReflog(m.reflogRef, max(len(m.reflog), reflogPageSize)),
+			func() tea.Msg {
+				return statusMsg{message: fmt.Sprintf("Created branch '%s' at %s %s", name, entry.Hash, entry.Subject)}
+			},
+		)()
+	}
+}
+
+// resetToReflog moves the current branch back to a reflog entry. --keep
+// carries uncommitted changes over and refuses when they would be lost.
+func (m model) resetToReflog(entry git.ReflogEntry) tea.Cmd {
+	return func() tea.Msg {
+		checkpoint, err := git.Checkpoint(m.repoPath, "reset to "+entry.Selector, nil)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Reset cancelled: %v", err)}
+		}
+		output, err := git.Execute(m.repoPath, "reset", "--keep", entry.Hash)
+		if err != nil {
+			git.DropCheckpoint(m.repoPath, checkpoint)
+			return statusMsg{message: fmt.Sprintf("Reset failed: %s", strings.TrimSpace(string(output)))}
+		}
+
+		return tea.Batch(
+			m.loadGitChanges(),
+			m.loadGitStatus(),
+			m.loadCommitHistory(),
+			m.loadReflog(m.reflogRef, max(len(m.reflog), reflogPageSize)),
+			func() tea.Msg {
+				return statusMsg{message: fmt.Sprintf("Reset to %s (%s)", entry.Selector, entry.Hash)}
+			},
+		)()
 	}
 }
 
 // Cherry-pick and Revert operations
 
-func (m model) cherryPickCommit(hash string) tea.Cmd {
+// cherryPickCommits applies the selected commits of the cherry-pick panel
+func (m model) cherryPickCommits() tea.Cmd {
+	var hashes []string
+	for i, commit := range m.pickCommits {
+		if m.pickSelected[i] {
+			hashes = append(hashes, commit.Hash)
+		}
+	}
+	opts := m.pickOpts
+	if m.pickMergeParents() == 0 {
+		opts.Mainline = 0
+	}
 	return func() tea.Msg {
-		err := git.CherryPick(m.repoPath, hash)
+		err := git.CherryPickCommits(m.repoPath, hashes, opts)
 		if err != nil {
-			return statusMsg{message: fmt.Sprintf("Cherry-pick failed: %v", err)}
+			// A conflict leaves the operation waiting; its panel takes over
+			state := git.GetOperationState(m.repoPath)
+			if state.Op != git.OpCherryPick {
+				return statusMsg{message: fmt.Sprintf("Cherry-pick failed: %v", err)}
+			}
+			return tea.Batch(m.loadGitChanges(), m.loadGitStatus(), func() tea.Msg {
+				return cherryPickMsg{message: operationStoppedMessage(state), stopped: true}
+			})()
 		}
 
+		message := fmt.Sprintf("Cherry-picked %d commits", len(hashes))
+		switch {
+		case opts.NoCommit:
+			message = fmt.Sprintf("Staged the changes of %d commits", len(hashes))
+		case len(hashes) == 1:
+			message = "Cherry-picked " + hashes[0]
+		}
 		return tea.Batch(
 			m.loadGitChanges(),
 			m.loadGitStatus(),
 			m.loadRecentCommits(),
 			func() tea.Msg {
-				return statusMsg{message: fmt.Sprintf("Cherry-picked %s", hash)}
+				return cherryPickMsg{message: message}
 			},
 		)()
 	}
 }
 
+// pickMergeParents is the most parents of a selected merge commit, 0 when
+// no merge is selected and no mainline is needed
+func (m model) pickMergeParents() int {
+	parents := 0
+	for i, commit := range m.pickCommits {
+		if m.pickSelected[i] && len(commit.Parents) > 1 {
+			parents = max(parents, len(commit.Parents))
+		}
+	}
+	return parents
+}
+
 func (m model) revertCommit(hash string) tea.Cmd {
 	return func() tea.Msg {
 		err := git.RevertCommit(m.repoPath, hash)
 		if err != nil {
+			// A conflict leaves the operation waiting; the banner takes over
+			state := git.GetOperationState(m.repoPath)
+			if state.Op == git.OpRevert && len(state.Conflicts) > 0 {
+				return tea.Batch(m.loadGitChanges(), m.loadGitStatus(), func() tea.Msg {
+					return statusMsg{message: operationStoppedMessage(state)}
+				})()
+			}
 			return statusMsg{message: fmt.Sprintf("Revert failed: %v", err)}
 		}
 
@@ -765,16 +1503,28 @@ func (m model) loadCleanFiles() tea.Cmd {
 
 func (m model) executeClean() tea.Cmd {
 	return func() tea.Msg {
-		err := git.CleanForce(m.repoPath)
+		// Untracked files have no other copy: back them up first
+		files, err := git.CleanDryRun(m.repoPath)
 		if err != nil {
 			return statusMsg{message: fmt.Sprintf("Clean failed: %v", err)}
 		}
+		if len(files) == 0 {
+			return statusMsg{message: "Nothing to clean"}
+		}
+		checkpoint, err := git.Checkpoint(m.repoPath, fmt.Sprintf("clean %d untracked files", len(files)), files)
+		if err != nil {
+			return statusMsg{message: fmt.Sprintf("Clean cancelled: %v", err)}
+		}
+		if err := git.CleanForce(m.repoPath, files); err != nil {
+			git.DropCheckpoint(m.repoPath, checkpoint)
+			return statusMsg{message: fmt.Sprintf("Clean failed: %v", err)}
+		}
 
 		return tea.Batch(
 			m.loadGitChanges(),
 			m.loadGitStatus(),
 			func() tea.Msg {
-				return statusMsg{message: "Cleaned untracked files"}
+				return statusMsg{message: "Cleaned untracked files (U to undo)"}
 			},
 		)()
 	}
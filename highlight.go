@@ -0,0 +1,143 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// span is a run of text drawn in one style
+type span struct {
+	text  string
+	style lipgloss.Style
+}
+
+// Texts bigger than this are shown without syntax highlighting
+const maxHighlightBytes = 1 << 20
+
+// Highlighting runs in commands as well as views, so the caches are locked
+var syntax = struct {
+	sync.Mutex
+	theme  *chroma.Style // nil disables highlighting
+	styles map[chroma.TokenType]lipgloss.Style
+	lexers map[string]chroma.Lexer
+}{
+	styles: map[chroma.TokenType]lipgloss.Style{},
+	lexers: map[string]chroma.Lexer{},
+}
+
+// setSyntaxTheme selects the chroma style for highlighting. An empty name
+// picks one to suit the terminal background and "none" turns it off. It
+// reports false for an unknown name, keeping the default.
+func setSyntaxTheme(name string) bool {
+	syntax.Lock()
+	defer syntax.Unlock()
+	clear(syntax.styles)
+
+	if name == "none" {
+		syntax.theme = nil
+		return true
+	}
+	theme, ok := styles.Registry[name]
+	if !ok {
+		theme = styles.Get("github")
+		if lipgloss.HasDarkBackground() {
+			theme = styles.Get("monokai")
+		}
+	}
+	syntax.theme = theme
+	return ok || name == ""
+}
+
+// highlightLines tokenizes lines as one text in the language of path and
+// splits the tokens back per line. It returns nil when the language is
+// unknown or highlighting is off.
+func highlightLines(path string, lines []string) [][]span {
+	lexer := lexerFor(path)
+	if lexer == nil {
+		return nil
+	}
+	text := strings.Join(lines, "\n")
+	if len(text) > maxHighlightBytes {
+		return nil
+	}
+	it, err := lexer.Tokenise(nil, text)
+	if err != nil {
+		return nil
+	}
+
+	out := make([][]span, len(lines))
+	row := 0
+	for tok := it(); tok != chroma.EOF; tok = it() {
+		style := tokenStyle(tok.Type)
+		for i, part := range strings.Split(tok.Value, "\n") {
+			if i > 0 {
+				row++
+			}
+			if row >= len(lines) {
+				break
+			}
+			if part != "" {
+				out[row] = append(out[row], span{text: part, style: style})
+			}
+		}
+	}
+
+	// Lexers may rewrite text (e.g. tabs or a final newline); fall back to
+	// plain text on any line that no longer matches
+	for i, line := range lines {
+		var sb strings.Builder
+		for _, s := range out[i] {
+			sb.WriteString(s.text)
+		}
+		if sb.String() != line {
+			out[i] = nil
+		}
+	}
+	return out
+}
+
+// plainSpans is text as a single unstyled span
+func plainSpans(text string) []span {
+	return []span{{text: text, style: lipgloss.NewStyle()}}
+}
+
+func lexerFor(path string) chroma.Lexer {
+	syntax.Lock()
+	defer syntax.Unlock()
+	if syntax.theme == nil || path == "" {
+		return nil
+	}
+	name := filepath.Base(path)
+	lexer, ok := syntax.lexers[name]
+	if !ok {
+		if lexer = lexers.Match(name); lexer != nil {
+			lexer = chroma.Coalesce(lexer)
+		}
+		syntax.lexers[name] = lexer
+	}
+	return lexer
+}
+
+func tokenStyle(t chroma.TokenType) lipgloss.Style {
+	syntax.Lock()
+	defer syntax.Unlock()
+	if style, ok := syntax.styles[t]; ok {
+		return style
+	}
+	style := lipgloss.NewStyle()
+	if syntax.theme != nil {
+		entry := syntax.theme.Get(t)
+		if entry.Colour.IsSet() {
+			style = style.Foreground(lipgloss.Color(entry.Colour.String()))
+		}
+		style = style.Bold(entry.Bold == chroma.Yes).Italic(entry.Italic == chroma.Yes)
+	}
+	syntax.styles[t] = style
+	return style
+}
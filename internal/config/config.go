@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LogPreset is a named log filter in ParseLogFilter syntax
+type LogPreset struct {
+	Name   string `json:"name"`
+	Filter string `json:"filter"`
+}
+
+// Config holds user settings from ~/.config/gitty/config.json
+type Config struct {
+	LogPresets []LogPreset `json:"log_presets,omitempty"`
+	// SyntaxTheme is a chroma style name, "none" to disable highlighting or
+	// empty to pick one for the terminal background
+	SyntaxTheme string `json:"syntax_theme,omitempty"`
+}
+
+func path() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "gitty", "config.json"), nil
+}
+
+// Load reads the config file. A missing file is an empty config.
+func Load() (Config, error) {
+	var cfg Config
+	p, err := path()
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("cannot read config: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", p, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the config file, replacing it atomically
+func (c Config) Save() error {
+	p, err := path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("cannot create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := p + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot write config: %w", err)
+	}
+	return os.Rename(tmp, p)
+}
+
+// SetLogPreset adds a preset or replaces the one with the same name
+func (c *Config) SetLogPreset(name, filter string) {
+	for i, p := range c.LogPresets {
+		if p.Name == name {
+			c.LogPresets[i].Filter = filter
+			return
+		}
+	}
+	c.LogPresets = append(c.LogPresets, LogPreset{Name: name, Filter: filter})
+}
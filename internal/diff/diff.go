@@ -0,0 +1,294 @@
+// Package diff parses unified diff output into lines that can be laid out
+// unified or side by side, with word-level changes marked on paired lines.
+package diff
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Kind int
+
+const (
+	Context Kind = iota
+	Added
+	Removed
+	Header    // diff --git, index, ---/+++, rename and mode lines
+	Hunk      // @@ -a,b +c,d @@
+	NoNewline // \ No newline at end of file
+)
+
+// Segment is a run of text within a changed line
+type Segment struct {
+	Text    string
+	Changed bool
+}
+
+// Line is one line of a unified diff. Text excludes the +/-/space prefix for
+// content lines. OldNum/NewNum are 0 where the line has no counterpart.
+type Line struct {
+	Kind     Kind
+	Text     string
+	OldNum   int
+	NewNum   int
+	Segments []Segment // word diff against the paired line, nil when unpaired
+}
+
+// Row is a side-by-side row. Header and hunk lines span both columns and are
+// set as Full; otherwise Left (old) and/or Right (new) are set.
+type Row struct {
+	Full  *Line
+	Left  *Line
+	Right *Line
+}
+
+// Parse splits unified diff output into lines, numbers them from the hunk
+// headers and computes word diffs between paired removed/added lines
+func Parse(unified string) []Line {
+	unified = strings.TrimRight(unified, "\n")
+	if unified == "" {
+		return nil
+	}
+
+	var lines []Line
+	oldNum, newNum := 0, 0
+	// Lines left in the current hunk; text after the last hunk line (such as
+	// a section title between two diffs) is a header again. -1 for combined
+	// diffs, which run until the next "diff" line.
+	oldLeft, newLeft := 0, 0
+	inHunk := func() bool { return oldLeft != 0 || newLeft != 0 }
+
+	for _, raw := range strings.Split(unified, "\n") {
+		switch {
+		case strings.HasPrefix(raw, "@@@"):
+			oldNum, newNum = parseHunkHeader(raw)
+			oldLeft, newLeft = -1, -1
+			lines = append(lines, Line{Kind: Hunk, Text: raw})
+		case strings.HasPrefix(raw, "@@"):
+			oldNum, newNum, oldLeft, newLeft = parseHunkRanges(raw)
+			lines = append(lines, Line{Kind: Hunk, Text: raw})
+		case strings.HasPrefix(raw, "\\"):
+			lines = append(lines, Line{Kind: NoNewline, Text: raw})
+		case strings.HasPrefix(raw, "diff "):
+			oldLeft, newLeft = 0, 0
+			lines = append(lines, Line{Kind: Header, Text: raw})
+		case !inHunk():
+			lines = append(lines, Line{Kind: Header, Text: raw})
+		case strings.HasPrefix(raw, "+"):
+			lines = append(lines, Line{Kind: Added, Text: raw[1:], NewNum: newNum})
+			newNum++
+			newLeft = countDown(newLeft)
+		case strings.HasPrefix(raw, "-"):
+			lines = append(lines, Line{Kind: Removed, Text: raw[1:], OldNum: oldNum})
+			oldNum++
+			oldLeft = countDown(oldLeft)
+		default:
+			lines = append(lines, Line{Kind: Context, Text: strings.TrimPrefix(raw, " "), OldNum: oldNum, NewNum: newNum})
+			oldNum++
+			newNum++
+			oldLeft, newLeft = countDown(oldLeft), countDown(newLeft)
+		}
+	}
+
+	forEachChange(lines, func(removed, added []int) {
+		for i := 0; i < len(removed) && i < len(added); i++ {
+			a, b := &lines[removed[i]], &lines[added[i]]
+			a.Segments, b.Segments = WordDiff(a.Text, b.Text)
+		}
+	})
+	return lines
+}
+
+// parseHunkHeader reads the starting line numbers from "@@ -a,b +c,d @@"
+func parseHunkHeader(header string) (int, int) {
+	oldStart, newStart, _, _ := parseHunkRanges(header)
+	return oldStart, newStart
+}
+
+// parseHunkRanges reads the starts and line counts from "@@ -a,b +c,d @@".
+// A missing count means one line.
+func parseHunkRanges(header string) (oldStart, newStart, oldCount, newCount int) {
+	fields := strings.Fields(header)
+	if len(fields) < 3 {
+		return 0, 0, 0, 0
+	}
+	parse := func(field string) (int, int) {
+		start, count, found := strings.Cut(strings.TrimLeft(field, "-+"), ",")
+		s, _ := strconv.Atoi(start)
+		if !found {
+			return s, 1
+		}
+		c, _ := strconv.Atoi(count)
+		return s, c
+	}
+	oldStart, oldCount = parse(fields[1])
+	newStart, newCount = parse(fields[2])
+	return oldStart, newStart, oldCount, newCount
+}
+
+// countDown decrements a hunk's remaining lines, leaving -1 (unbounded)
+// and 0 alone
+func countDown(n int) int {
+	if n > 0 {
+		return n - 1
+	}
+	return n
+}
+
+// forEachChange calls fn for every block of removed lines followed by added
+// lines (either may be empty), passing their indexes
+func forEachChange(lines []Line, fn func(removed, added []int)) {
+	for i := 0; i < len(lines); {
+		if lines[i].Kind != Removed && lines[i].Kind != Added {
+			i++
+			continue
+		}
+		var removed, added []int
+		for i < len(lines) && (lines[i].Kind == Removed || lines[i].Kind == NoNewline) {
+			if lines[i].Kind == Removed {
+				removed = append(removed, i)
+			}
+			i++
+		}
+		for i < len(lines) && (lines[i].Kind == Added || lines[i].Kind == NoNewline) {
+			if lines[i].Kind == Added {
+				added = append(added, i)
+			}
+			i++
+		}
+		fn(removed, added)
+	}
+}
+
+// SideBySide pairs removed and added lines into rows, old on the left
+func SideBySide(lines []Line) []Row {
+	var rows []Row
+	for i := 0; i < len(lines); {
+		line := &lines[i]
+		switch line.Kind {
+		case Header, Hunk:
+			rows = append(rows, Row{Full: line})
+			i++
+		case Context:
+			rows = append(rows, Row{Left: line, Right: line})
+			i++
+		case NoNewline:
+			i++
+		default:
+			// Collect the change block and zip its two sides
+			start := i
+			var removed, added []*Line
+			for i < len(lines) && (lines[i].Kind == Removed || lines[i].Kind == NoNewline) {
+				if lines[i].Kind == Removed {
+					removed = append(removed, &lines[i])
+				}
+				i++
+			}
+			for i < len(lines) && (lines[i].Kind == Added || lines[i].Kind == NoNewline) {
+				if lines[i].Kind == Added {
+					added = append(added, &lines[i])
+				}
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			for j := 0; j < max(len(removed), len(added)); j++ {
+				var row Row
+				if j < len(removed) {
+					row.Left = removed[j]
+				}
+				if j < len(added) {
+					row.Right = added[j]
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
+
+// maxWordDiffTokens bounds the quadratic LCS; longer lines are marked as
+// changed wholesale
+const maxWordDiffTokens = 400
+
+// WordDiff splits a and b into words, whitespace and punctuation and marks
+// the tokens not in their longest common subsequence as changed
+func WordDiff(a, b string) ([]Segment, []Segment) {
+	ta, tb := tokenize(a), tokenize(b)
+	if len(ta) > maxWordDiffTokens || len(tb) > maxWordDiffTokens {
+		return []Segment{{Text: a, Changed: true}}, []Segment{{Text: b, Changed: true}}
+	}
+
+	// lcs[i][j] is the LCS length of ta[i:] and tb[j:]
+	lcs := make([][]int, len(ta)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(tb)+1)
+	}
+	for i := len(ta) - 1; i >= 0; i-- {
+		for j := len(tb) - 1; j >= 0; j-- {
+			if ta[i] == tb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sa, sb []Segment
+	i, j := 0, 0
+	for i < len(ta) || j < len(tb) {
+		switch {
+		case i < len(ta) && j < len(tb) && ta[i] == tb[j]:
+			sa = appendSegment(sa, ta[i], false)
+			sb = appendSegment(sb, tb[j], false)
+			i++
+			j++
+		case j < len(tb) && (i == len(ta) || lcs[i][j+1] >= lcs[i+1][j]):
+			sb = appendSegment(sb, tb[j], true)
+			j++
+		default:
+			sa = appendSegment(sa, ta[i], true)
+			i++
+		}
+	}
+	return sa, sb
+}
+
+// appendSegment merges adjacent tokens with the same state
+func appendSegment(segs []Segment, text string, changed bool) []Segment {
+	if n := len(segs); n > 0 && segs[n-1].Changed == changed {
+		segs[n-1].Text += text
+		return segs
+	}
+	return append(segs, Segment{Text: text, Changed: changed})
+}
+
+// tokenize splits into runs of letters/digits/underscore, runs of
+// whitespace, and single other characters
+func tokenize(s string) []string {
+	var tokens []string
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		j := i + 1
+		switch {
+		case isWordRune(runes[i]):
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+		case unicode.IsSpace(runes[i]):
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+		}
+		tokens = append(tokens, string(runes[i:j]))
+		i = j
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,295 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for image.DecodeConfig
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// fileSource is where one side of a diff reads a file from
+type fileSource int
+
+const (
+	fromNone fileSource = iota
+	fromHEAD
+	fromIndex
+	fromWorktree
+)
+
+// BlobInfo describes one version of a file that is not shown as text
+type BlobInfo struct {
+	Exists bool
+	Size   int64
+	Format string // image format ("png", "jpeg", "gif") when decodable
+	Width  int
+	Height int
+}
+
+// LFSPointer is the content git stores in place of a Git LFS object
+type LFSPointer struct {
+	Oid  string // sha256 of the object
+	Size int64
+}
+
+const (
+	lfsPointerVersion = "version https://git-lfs.github.com/spec/v1"
+	// Image headers are read up to this size; JPEG dimensions can sit
+	// behind large EXIF blocks
+	imageHeaderBytes = 1 << 20
+)
+
+// annotateDiff rewrites the parts of a diff that are not useful as text:
+// "Binary files differ" becomes sizes and image metadata, and LFS pointer
+// hunks become a summary of the object change. paths are the candidate
+// paths of the change; oldSrc/newSrc say where each side is read from.
+func annotateDiff(repoPath, text string, paths []string, oldSrc, newSrc fileSource) string {
+	if text == "" {
+		return text
+	}
+	var out []string
+	for _, section := range splitDiffSections(text) {
+		out = append(out, annotateSection(repoPath, section, paths, oldSrc, newSrc)...)
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// splitDiffSections splits diff output into one line slice per file
+func splitDiffSections(text string) [][]string {
+	var sections [][]string
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if strings.HasPrefix(line, "diff ") || len(sections) == 0 {
+			sections = append(sections, nil)
+		}
+		sections[len(sections)-1] = append(sections[len(sections)-1], line)
+	}
+	return sections
+}
+
+func annotateSection(repoPath string, lines []string, paths []string, oldSrc, newSrc fileSource) []string {
+	oldPath, newPath, ok := sectionPaths(lines[0], paths)
+	if !ok {
+		return lines
+	}
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, "Binary files ") && strings.HasSuffix(line, " differ") {
+			oldInfo := statFile(repoPath, oldSrc, oldPath)
+			newInfo := statFile(repoPath, newSrc, newPath)
+			summary := describeBinary(oldInfo, newInfo)
+			return append(append(lines[:i:i], summary...), lines[i+1:]...)
+		}
+	}
+
+	if oldPtr, newPtr, ok := parseLFSPointers(lines); ok {
+		hunk := len(lines)
+		for i, line := range lines {
+			if strings.HasPrefix(line, "@@") {
+				hunk = i
+				break
+			}
+		}
+		return append(lines[:hunk:hunk], describeLFS(oldPtr, newPtr)...)
+	}
+	return lines
+}
+
+// sectionPaths matches "diff --git a/X b/Y" against the change's paths
+func sectionPaths(header string, paths []string) (string, string, bool) {
+	for _, o := range paths {
+		for _, n := range paths {
+			if header == "diff --git a/"+o+" b/"+n {
+				return o, n, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// statFile reads the size and, for images, the format and dimensions of
+// one version of path
+func statFile(repoPath string, src fileSource, path string) BlobInfo {
+	var info BlobInfo
+	switch src {
+	case fromNone:
+		return info
+	case fromWorktree:
+		st, err := os.Stat(filepath.Join(repoPath, path))
+		if err != nil || st.IsDir() {
+			return info
+		}
+		info.Exists, info.Size = true, st.Size()
+	default:
+		cmd := exec.Command("git", "cat-file", "-s", blobSpec(src, path))
+		cmd.Dir = repoPath
+		output, err := cmd.Output()
+		if err != nil {
+			return info
+		}
+		info.Size, _ = strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64)
+		info.Exists = true
+	}
+
+	if head := readFileHead(repoPath, src, path, imageHeaderBytes); head != nil {
+		if cfg, format, err := image.DecodeConfig(bytes.NewReader(head)); err == nil {
+			info.Format, info.Width, info.Height = format, cfg.Width, cfg.Height
+		}
+	}
+	return info
+}
+
+func blobSpec(src fileSource, path string) string {
+	if src == fromHEAD {
+		return "HEAD:" + path
+	}
+	return ":" + path
+}
+
+// readFileHead returns up to n bytes from the start of one version of path
+func readFileHead(repoPath string, src fileSource, path string, n int64) []byte {
+	if src == fromWorktree {
+		f, err := os.Open(filepath.Join(repoPath, path))
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		head, _ := io.ReadAll(io.LimitReader(f, n))
+		return head
+	}
+
+	cmd := exec.Command("git", "cat-file", "blob", blobSpec(src, path))
+	cmd.Dir = repoPath
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil
+	}
+	if err := cmd.Start(); err != nil {
+		return nil
+	}
+	head, _ := io.ReadAll(io.LimitReader(stdout, n))
+	// Large blobs are cut short; the rest isn't needed
+	cmd.Process.Kill()
+	cmd.Wait()
+	return head
+}
+
+func describeBinary(oldInfo, newInfo BlobInfo) []string {
+	var lines []string
+	switch {
+	case !oldInfo.Exists && newInfo.Exists:
+		lines = append(lines, "Binary file added: "+formatSize(newInfo.Size))
+	case oldInfo.Exists && !newInfo.Exists:
+		lines = append(lines, "Binary file deleted: "+formatSize(oldInfo.Size))
+	case oldInfo.Exists && newInfo.Exists:
+		lines = append(lines, fmt.Sprintf("Binary file: %s → %s (%s)",
+			formatSize(oldInfo.Size), formatSize(newInfo.Size), formatSizeDelta(newInfo.Size-oldInfo.Size)))
+	default:
+		lines = append(lines, "Binary file")
+	}
+
+	image := func(info BlobInfo) string {
+		if !info.Exists {
+			return "none"
+		}
+		if info.Format == "" {
+			return "not an image"
+		}
+		return fmt.Sprintf("%s %d×%d", strings.ToUpper(info.Format), info.Width, info.Height)
+	}
+	switch {
+	case oldInfo.Format != "" && newInfo.Format != "":
+		lines = append(lines, fmt.Sprintf("Image: %s → %s", image(oldInfo), image(newInfo)))
+	case newInfo.Format != "":
+		lines = append(lines, "Image: "+image(newInfo))
+	case oldInfo.Format != "":
+		lines = append(lines, "Image: "+image(oldInfo))
+	}
+	return lines
+}
+
+// parseLFSPointers reads the old and new pointer from a diff of an LFS
+// pointer file. Context lines belong to both sides.
+func parseLFSPointers(lines []string) (LFSPointer, LFSPointer, bool) {
+	var oldPtr, newPtr LFSPointer
+	found := false
+	inHunk := false
+	for _, line := range lines {
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+			continue
+		}
+		if !inHunk || line == "" {
+			continue
+		}
+		prefix, content := line[0], line[1:]
+		if content == lfsPointerVersion {
+			found = true
+			continue
+		}
+		key, value, ok := strings.Cut(content, " ")
+		if !ok {
+			continue
+		}
+		apply := func(p *LFSPointer) {
+			switch key {
+			case "oid":
+				p.Oid = strings.TrimPrefix(value, "sha256:")
+			case "size":
+				p.Size, _ = strconv.ParseInt(value, 10, 64)
+			}
+		}
+		if prefix == '-' || prefix == ' ' {
+			apply(&oldPtr)
+		}
+		if prefix == '+' || prefix == ' ' {
+			apply(&newPtr)
+		}
+	}
+	return oldPtr, newPtr, found
+}
+
+func describeLFS(oldPtr, newPtr LFSPointer) []string {
+	short := func(oid string) string {
+		if len(oid) > 12 {
+			return oid[:12]
+		}
+		return oid
+	}
+	switch {
+	case oldPtr.Oid == "":
+		return []string{fmt.Sprintf("Git LFS object added: %s (oid %s)", formatSize(newPtr.Size), short(newPtr.Oid))}
+	case newPtr.Oid == "":
+		return []string{fmt.Sprintf("Git LFS object deleted: %s (oid %s)", formatSize(oldPtr.Size), short(oldPtr.Oid))}
+	}
+	return []string{
+		fmt.Sprintf("Git LFS object: %s → %s (%s)", formatSize(oldPtr.Size), formatSize(newPtr.Size), formatSizeDelta(newPtr.Size-oldPtr.Size)),
+		fmt.Sprintf("oid %s → %s", short(oldPtr.Oid), short(newPtr.Oid)),
+	}
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+func formatSizeDelta(n int64) string {
+	if n < 0 {
+		return "-" + formatSize(-n)
+	}
+	return "+" + formatSize(n)
+}
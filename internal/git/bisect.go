@@ -0,0 +1,160 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BisectState is a bisect in progress
+type BisectState struct {
+	Bad        string   // short hash of the bad end
+	Good       []string // short hashes marked good
+	Skipped    []string // short hashes skipped
+	Current    *Commit  // checked-out commit being tested
+	Candidates int      // commits that may still be the first bad one
+	Steps      int      // estimated steps left; a lower bound once commits are skipped
+	FirstBad   *Commit  // set once the bisect has found it
+}
+
+// GetBisectState reads the bisect refs; nil when no bisect is running
+func GetBisectState(repoPath string) *BisectState {
+	dir, err := gitDir(repoPath)
+	if err != nil {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, "BISECT_LOG")); err != nil {
+		return nil
+	}
+
+	var state BisectState
+	cmd := exec.Command("git", "for-each-ref", "--format=%(refname) %(objectname:short)", "refs/bisect/")
+	cmd.Dir = repoPath
+	output, _ := cmd.Output()
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		ref, hash, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		switch {
+		case ref == "refs/bisect/bad":
+			state.Bad = hash
+		case strings.HasPrefix(ref, "refs/bisect/good-"):
+			state.Good = append(state.Good, hash)
+		case strings.HasPrefix(ref, "refs/bisect/skip-"):
+			state.Skipped = append(state.Skipped, hash)
+		}
+	}
+
+	if commits := compareLog(repoPath, "-1", "HEAD"); len(commits) > 0 {
+		state.Current = &commits[0]
+	}
+
+	// Until both ends are marked there is nothing to count
+	if state.Bad == "" || len(state.Good) == 0 {
+		return &state
+	}
+	args := []string{"rev-list", "--bisect-vars", "refs/bisect/bad", "--not"}
+	args = append(args, state.Good...)
+	cmd = exec.Command("git", args...)
+	cmd.Dir = repoPath
+	output, _ = cmd.Output()
+	for _, line := range strings.Split(string(output), "\n") {
+		name, value, _ := strings.Cut(line, "=")
+		value = strings.Trim(value, "'")
+		switch name {
+		case "bisect_all":
+			state.Candidates, _ = strconv.Atoi(value)
+		case "bisect_steps":
+			state.Steps, _ = strconv.Atoi(value)
+		}
+	}
+
+	// Only the bad commit itself left: it is the first bad one
+	if state.Candidates == 1 {
+		if commits := compareLog(repoPath, "-1", "refs/bisect/bad"); len(commits) > 0 {
+			state.FirstBad = &commits[0]
+		}
+		state.Steps = 0
+	}
+	return &state
+}
+
+// StartBisect checks out the midpoint between a good and a bad commit
+func StartBisect(repoPath, bad, good string) (string, error) {
+	return bisectCommand(repoPath, "start", bad, good)
+}
+
+// MarkBisect marks revs (default: the checked-out commit) "good", "bad" or
+// "skip" and checks out the next commit to test
+func MarkBisect(repoPath, mark string, revs ...string) (string, error) {
+	return bisectCommand(repoPath, append([]string{mark}, revs...)...)
+}
+
+// ResetBisect ends the bisect and returns to the branch it started on
+func ResetBisect(repoPath string) error {
+	return AbortOperation(repoPath, OpBisect)
+}
+
+func bisectCommand(repoPath string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"bisect"}, args...)...)
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// BisectRun is a `git bisect run` in progress
+type BisectRun struct {
+	Lines <-chan string // combined output; closed when the run ends
+	cmd   *exec.Cmd
+	err   error
+}
+
+// StartBisectRun runs command through sh at every step until the first bad
+// commit is found. Exit 0 marks good, 125 skip, other codes below 128 bad.
+func StartBisectRun(repoPath, command string) (*BisectRun, error) {
+	cmd := exec.Command("git", "bisect", "run", "sh", "-c", command)
+	cmd.Dir = repoPath
+	pr, pw := io.Pipe()
+	cmd.Stdout, cmd.Stderr = pw, pw
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("bisect run failed: %w", err)
+	}
+
+	lines := make(chan string, 64)
+	run := &BisectRun{Lines: lines, cmd: cmd}
+	go func() {
+		run.err = cmd.Wait()
+		pw.Close()
+	}()
+	go func() {
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		// Keep draining after a scan error so git never blocks on output
+		io.Copy(io.Discard, pr)
+		close(lines)
+	}()
+	return run, nil
+}
+
+// Err is the result of the run once Lines is closed
+func (r *BisectRun) Err() error {
+	return r.err
+}
+
+// Stop kills the run; the bisect stays at the step it reached
+func (r *BisectRun) Stop() {
+	if r.cmd.Process != nil {
+		r.cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,167 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type BlameLine struct {
+	Hash     string
+	FullHash string
+	Author   string
+	Date     string
+	Summary  string
+	LineNum  int
+	OrigLine int    // line number in the commit that introduced it
+	Filename string // path in that commit; differs after renames or with copy detection
+	PrevHash string // parent to blame past this commit, "" for root/boundary commits
+	PrevPath string
+	Content  string
+}
+
+// IsUncommitted reports whether the line only exists in the working tree
+func (l BlameLine) IsUncommitted() bool {
+	return strings.Trim(l.FullHash, "0") == ""
+}
+
+// BlameOptions controls which revision is blamed and how origins are detected
+type BlameOptions struct {
+	Rev              string // "" blames the working tree
+	IgnoreWhitespace bool   // -w
+	DetectMoves      bool   // -M: lines moved within the file
+	DetectCopies     bool   // -C: lines moved or copied from other files
+}
+
+// blameCommit holds the headers porcelain output prints once per commit
+type blameCommit struct {
+	author, date, summary string
+	prevHash, prevPath    string
+}
+
+// GetBlame runs `git blame --porcelain` on filePath. The ignore-revs file
+// (see BlameIgnoreRevsFile) is applied so reformat commits are skipped.
+func GetBlame(repoPath, filePath string, opts BlameOptions) ([]BlameLine, error) {
+	args := []string{"blame", "--porcelain"}
+	if opts.IgnoreWhitespace {
+		args = append(args, "-w")
+	}
+	if opts.DetectMoves {
+		args = append(args, "-M")
+	}
+	if opts.DetectCopies {
+		args = append(args, "-C")
+	}
+	// git reads blame.ignoreRevsFile itself; only the conventional file needs passing
+	if ignoreFile := BlameIgnoreRevsFile(repoPath); ignoreFile != "" && gitConfig(repoPath, "blame.ignoreRevsFile") == "" {
+		args = append(args, "--ignore-revs-file", ignoreFile)
+	}
+	if opts.Rev != "" {
+		args = append(args, opts.Rev)
+	}
+	args = append(args, "--", filePath)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("blame failed: %s", strings.TrimSpace(string(output)))
+	}
+	return parseBlamePorcelain(string(output)), nil
+}
+
+func parseBlamePorcelain(output string) []BlameLine {
+	var lines []BlameLine
+	commits := map[string]*blameCommit{}
+	var current *blameCommit
+	var hash, filename string
+	origLine := 0
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			lines = append(lines, BlameLine{
+				Hash:     ShortHash(hash),
+				FullHash: hash,
+				Author:   current.author,
+				Date:     current.date,
+				Summary:  current.summary,
+				LineNum:  len(lines) + 1,
+				OrigLine: origLine,
+				Filename: filename,
+				PrevHash: current.prevHash,
+				PrevPath: current.prevPath,
+				Content:  strings.TrimPrefix(line, "\t"),
+			})
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			current.author = value
+		case "author-time":
+			if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.date = time.Unix(timestamp, 0).Format("2006-01-02")
+			}
+		case "summary":
+			current.summary = value
+		case "previous":
+			current.prevHash, current.prevPath, _ = strings.Cut(value, " ")
+		case "filename":
+			filename = value
+		default:
+			// Group header: <hash> <orig line> <final line> [<lines in group>]
+			fields := strings.Fields(line)
+			if len(fields) >= 3 && len(fields[0]) >= 40 && isHex(fields[0]) {
+				hash = fields[0]
+				origLine, _ = strconv.Atoi(fields[1])
+				if commits[hash] == nil {
+					commits[hash] = &blameCommit{}
+				}
+				current = commits[hash]
+			}
+		}
+	}
+
+	return lines
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// BlameIgnoreRevsFile returns the ignore-revs file blame uses: the
+// blame.ignoreRevsFile setting, or .git-blame-ignore-revs at the repo root
+func BlameIgnoreRevsFile(repoPath string) string {
+	if configured := gitConfig(repoPath, "blame.ignoreRevsFile"); configured != "" {
+		return configured
+	}
+	if _, err := os.Stat(filepath.Join(repoPath, ".git-blame-ignore-revs")); err == nil {
+		return ".git-blame-ignore-revs"
+	}
+	return ""
+}
+
+func gitConfig(repoPath, key string) string {
+	cmd := exec.Command("git", "config", "--get", key)
+	cmd.Dir = repoPath
+	output, _ := cmd.Output()
+	return strings.TrimSpace(string(output))
+}
+
+// GetCommitMessage returns the full message (subject and body) of hash
+func GetCommitMessage(repoPath, hash string) string {
+	cmd := exec.Command("git", "log", "-1", "--format=%B", hash)
+	cmd.Dir = repoPath
+	output, _ := cmd.Output()
+	return strings.TrimRight(string(output), "\n")
+}
@@ -0,0 +1,120 @@
+package git
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ParseCompareRange reads "target..source", "target...source" or a single
+// ref, which is compared with HEAD using the merge base
+func ParseCompareRange(spec string) (target, source string, threeDot bool) {
+	spec = strings.TrimSpace(spec)
+	if target, source, ok := strings.Cut(spec, "..."); ok {
+		return target, orHEAD(source), true
+	}
+	if target, source, ok := strings.Cut(spec, ".."); ok {
+		return target, orHEAD(source), false
+	}
+	return spec, "HEAD", true
+}
+
+func orHEAD(ref string) string {
+	if ref == "" {
+		return "HEAD"
+	}
+	return ref
+}
+
+// GetBranchComparison lists the commits each side has that the other lacks,
+// the merge base and the changed files with their line counts
+func GetBranchComparison(repoPath, source, target string, threeDot bool) BranchComparison {
+	comparison := BranchComparison{
+		SourceBranch:   source,
+		TargetBranch:   target,
+		ThreeDot:       threeDot,
+		AheadCommits:   compareLog(repoPath, target+".."+source),
+		BehindCommits:  compareLog(repoPath, source+".."+target),
+		DifferingFiles: []string{},
+		Files:          []FileStat{},
+	}
+
+	cmd := exec.Command("git", "merge-base", target, source)
+	cmd.Dir = repoPath
+	if output, err := cmd.Output(); err == nil {
+		if base := compareLog(repoPath, "-1", strings.TrimSpace(string(output))); len(base) > 0 {
+			comparison.MergeBase = &base[0]
+		}
+	}
+
+	args := append([]string{"diff", "--numstat", "-z", "-M"}, comparison.diffRange()...)
+	cmd = exec.Command("git", args...)
+	cmd.Dir = repoPath
+	if output, err := cmd.Output(); err == nil {
+		comparison.Files = parseNumstat(string(output))
+	}
+	for _, f := range comparison.Files {
+		comparison.DifferingFiles = append(comparison.DifferingFiles, f.Path)
+		comparison.Insertions += f.Added
+		comparison.Deletions += f.Deleted
+	}
+	comparison.Merge = PredictMerge(repoPath, source, target)
+	return comparison
+}
+
+// GetComparisonFileDiff diffs one file of a comparison. Pass both sides of
+// a rename so git can pair them up.
+func GetComparisonFileDiff(repoPath string, comparison BranchComparison, opts DiffOptions, paths ...string) string {
+	args := append([]string{"diff"}, opts.args()...)
+	args = append(args, comparison.diffRange()...)
+	args = append(args, "--")
+	args = append(args, paths...)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	output, _ := cmd.Output()
+	return string(output)
+}
+
+func (c BranchComparison) diffRange() []string {
+	if c.ThreeDot {
+		return []string{c.TargetBranch + "..." + c.SourceBranch}
+	}
+	return []string{c.TargetBranch, c.SourceBranch}
+}
+
+func compareLog(repoPath string, args ...string) []Commit {
+	cmd := exec.Command("git", append([]string{"log", logFormat, "--decorate=full"}, args...)...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return []Commit{}
+	}
+	commits := parseCommitLog(string(output))
+	if commits == nil {
+		return []Commit{}
+	}
+	return commits
+}
+
+// parseNumstat reads `git diff --numstat -z`: "added\tdeleted\tpath\0", or
+// for renames "added\tdeleted\t\0old\0new\0". Binary files count "-".
+func parseNumstat(output string) []FileStat {
+	var stats []FileStat
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		parts := strings.SplitN(fields[i], "\t", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		stat := FileStat{Path: parts[2], Binary: parts[0] == "-"}
+		stat.Added, _ = strconv.Atoi(parts[0])
+		stat.Deleted, _ = strconv.Atoi(parts[1])
+		if stat.Path == "" && i+2 < len(fields) {
+			stat.OrigPath, stat.Path = fields[i+1], fields[i+2]
+			i += 2
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
@@ -0,0 +1,116 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Rename detection modes for DiffOptions
+const (
+	RenamesOn     = ""       // -M
+	RenamesCopies = "copies" // -C, renames and copies
+	RenamesOff    = "off"    // --no-renames
+)
+
+// DiffOptions are the diff toggles shared by the workspace diff views
+type DiffOptions struct {
+	IgnoreWhitespace bool   // -w
+	Context          int    // -U<n>; 0 keeps git's default of 3
+	Renames          string // RenamesOn, RenamesCopies or RenamesOff
+}
+
+func (o DiffOptions) args() []string {
+	var args []string
+	switch o.Renames {
+	case RenamesCopies:
+		args = append(args, "-C")
+	case RenamesOff:
+		args = append(args, "--no-renames")
+	default:
+		args = append(args, "-M")
+	}
+	if o.IgnoreWhitespace {
+		args = append(args, "-w")
+	}
+	if o.Context > 0 {
+		args = append(args, fmt.Sprintf("-U%d", o.Context))
+	}
+	return args
+}
+
+// ChangeDiff is the diff of one status entry, split by where the change
+// lives. A partially staged file has both Staged and Unstaged set. Binary
+// files and Git LFS pointers are summarized instead (see annotateDiff).
+type ChangeDiff struct {
+	Staged    string // index vs HEAD
+	Unstaged  string // working tree vs index (combined diff when conflicted)
+	Untracked string // whole content of new files, which git diff never shows
+}
+
+// maxUntrackedDiffFiles bounds the files shown for an untracked directory
+const maxUntrackedDiffFiles = 20
+
+// GetFileDiff diffs the given paths. Pass both sides of a rename so git
+// can pair them up.
+func GetFileDiff(repoPath string, staged bool, opts DiffOptions, paths ...string) string {
+	args := append([]string{"diff"}, opts.args()...)
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, "--")
+	args = append(args, paths...)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	output, _ := cmd.Output()
+	return string(output)
+}
+
+// GetChangeDiff diffs every side of a change: staged and unstaged for
+// tracked files, or the content of untracked ones
+func GetChangeDiff(repoPath string, change Change, opts DiffOptions) ChangeDiff {
+	var d ChangeDiff
+	if change.Kind == ChangeUntracked {
+		d.Untracked = getUntrackedDiff(repoPath, change.File, opts)
+		return d
+	}
+	paths := change.Paths()
+	if change.IsStaged() {
+		d.Staged = annotateDiff(repoPath, GetFileDiff(repoPath, true, opts, paths...), paths, fromHEAD, fromIndex)
+	}
+	if change.IsConflict() {
+		d.Unstaged = GetFileDiff(repoPath, false, opts, paths...)
+	} else if change.HasWorktreeChanges() {
+		d.Unstaged = annotateDiff(repoPath, GetFileDiff(repoPath, false, opts, paths...), paths, fromIndex, fromWorktree)
+	}
+	return d
+}
+
+// getUntrackedDiff diffs untracked files against /dev/null. Porcelain lists
+// untracked directories as one entry, so they are expanded first.
+func getUntrackedDiff(repoPath, path string, opts DiffOptions) string {
+	cmd := exec.Command("git", "ls-files", "--others", "--exclude-standard", "-z", "--", path)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	files := splitNul(string(output))
+
+	var sb strings.Builder
+	for i, file := range files {
+		if i == maxUntrackedDiffFiles {
+			fmt.Fprintf(&sb, "... %d more untracked files\n", len(files)-i)
+			break
+		}
+		args := append([]string{"diff", "--no-index"}, opts.args()...)
+		args = append(args, "--", "/dev/null", file)
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoPath
+		// Exit status 1 means the files differ, which they always do here
+		output, _ := cmd.Output()
+		sb.WriteString(annotateDiff(repoPath, string(output), []string{file}, fromNone, fromWorktree))
+	}
+	return sb.String()
+}
@@ -0,0 +1,74 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// FileRevision is a commit in a file's history with the path the file had
+// in that commit, which differs from the current path before a rename
+type FileRevision struct {
+	Commit
+	Path string `json:"path"`
+}
+
+// GetFileHistory returns up to count commits touching path, following
+// renames, starting at rev and skipping the first skip. git ignores --skip
+// together with --follow, so earlier pages are fetched again and dropped.
+func GetFileHistory(repoPath, rev, path string, skip, count int) []FileRevision {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	cmd := exec.Command("git", "log", "--follow", "-M", fmt.Sprintf("-%d", skip+count),
+		"--decorate=full", "--format=%x1e"+strings.TrimPrefix(logFormat, "--pretty=format:"),
+		"--name-only", "-z", rev, "--", path)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var revisions []FileRevision
+	for _, record := range strings.Split(string(output), "\x1e") {
+		// <header>\x00\n<path>\x00
+		header, rest, ok := strings.Cut(record, "\x00")
+		if !ok {
+			continue
+		}
+		commits := parseCommitLog(header)
+		if len(commits) == 0 {
+			continue
+		}
+		filePath, _, _ := strings.Cut(strings.TrimPrefix(rest, "\n"), "\x00")
+		if filePath == "" {
+			filePath = path
+		}
+		revisions = append(revisions, FileRevision{Commit: commits[0], Path: filePath})
+	}
+
+	if skip >= len(revisions) {
+		return nil
+	}
+	return revisions[skip:]
+}
+
+// GetFileRevisionDiff returns what a single commit changed in path,
+// including the rename header when the commit renamed it
+func GetFileRevisionDiff(repoPath, hash, path string) string {
+	cmd := exec.Command("git", "log", "-1", "-p", "-M", "--follow", "--format=", hash, "--", path)
+	cmd.Dir = repoPath
+	output, _ := cmd.Output()
+	return string(output)
+}
+
+// GetFileAtRevision returns the content of path as of hash
+func GetFileAtRevision(repoPath, hash, path string) (string, error) {
+	cmd := exec.Command("git", "show", hash+":"+path)
+	cmd.Dir = repoPath
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("show failed: %s", strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
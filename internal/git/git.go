@@ -14,35 +14,41 @@ import (
 // Types
 
 type Change struct {
-	File   string
-	Status string
-	Type   string
-	Scope  string
+	File      string `json:"file"`
+	Status    string `json:"status"`
+	Kind      string `json:"kind"`
+	OrigPath  string `json:"orig_path,omitempty"` // source of a rename/copy
+	Score     int    `json:"score,omitempty"`     // rename/copy similarity percentage
+	Submodule string `json:"submodule,omitempty"` // "S<c><m><u>" for submodules
+	Type      string `json:"type,omitempty"`
+	Scope     string `json:"scope,omitempty"`
 }
 
 type Status struct {
-	Branch        string
-	Clean         bool
-	StagedFiles   int
-	UnstagedFiles int
-	Ahead         int
-	Behind        int
+	Branch        string `json:"branch"`
+	Clean         bool   `json:"clean"`
+	StagedFiles   int    `json:"staged_files"`
+	UnstagedFiles int    `json:"unstaged_files"`
+	Ahead         int    `json:"ahead"`
+	Behind        int    `json:"behind"`
 }
 
 type Branch struct {
-	Name      string
-	IsCurrent bool
-	IsRemote  bool
-	Upstream  string
-	Ahead     int
-	Behind    int
+	Name      string `json:"name"`
+	IsCurrent bool   `json:"is_current"`
+	IsRemote  bool   `json:"is_remote"`
+	Upstream  string `json:"upstream,omitempty"`
+	Ahead     int    `json:"ahead"`
+	Behind    int    `json:"behind"`
 }
 
 type Commit struct {
-	Hash    string
-	Message string
-	Author  string
-	Date    string
+	Hash    string   `json:"hash"`
+	Message string   `json:"message"`
+	Author  string   `json:"author,omitempty"`
+	Date    string   `json:"date"`
+	Parents []string `json:"parents,omitempty"`
+	Refs    []string `json:"refs,omitempty"` // full decorations: "HEAD -> refs/heads/main", "tag: refs/tags/v1.0"
 }
 
 type ConflictFile struct {
@@ -50,33 +56,53 @@ type ConflictFile struct {
 	IsResolved bool
 }
 
+// BranchComparison compares two refs. With ThreeDot the file changes are
+// those made on the source since the merge base (target...source), otherwise
+// the difference between the two trees (target..source).
 type BranchComparison struct {
-	SourceBranch   string
-	TargetBranch   string
-	AheadCommits   []Commit
-	BehindCommits  []Commit
-	DifferingFiles []string
+	SourceBranch   string          `json:"source_branch"`
+	TargetBranch   string          `json:"target_branch"`
+	ThreeDot       bool            `json:"three_dot"`
+	MergeBase      *Commit         `json:"merge_base,omitempty"`
+	AheadCommits   []Commit        `json:"ahead_commits"`
+	BehindCommits  []Commit        `json:"behind_commits"`
+	DifferingFiles []string        `json:"differing_files"`
+	Files          []FileStat      `json:"files"`
+	Insertions     int             `json:"insertions"`
+	Deletions      int             `json:"deletions"`
+	Merge          MergePrediction `json:"merge"` // merging target into source
+}
+
+// FileStat is one file's line counts in a diff
+type FileStat struct {
+	Path     string `json:"path"`
+	OrigPath string `json:"orig_path,omitempty"` // source of a rename
+	Added    int    `json:"added"`
+	Deleted  int    `json:"deleted"`
+	Binary   bool   `json:"binary,omitempty"`
 }
 
 type RebaseCommit struct {
-	Hash    string
-	Message string
-	Action  string
+	Hash       string
+	Message    string
+	Action     string // pick, reword, edit, squash, fixup, drop or exec
+	NewMessage string // reword: replacement message, set inline
+	Command    string // exec: shell command run at this point of the plan
 }
 
 type Stash struct {
-	Index   int
-	Message string
-	Date    string
-	Branch  string
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+	Date    string `json:"date"`
+	Branch  string `json:"branch,omitempty"`
 }
 
 type Tag struct {
-	Name        string
-	Message     string
-	Commit      string
-	Date        string
-	IsAnnotated bool
+	Name        string `json:"name"`
+	Message     string `json:"message,omitempty"`
+	Commit      string `json:"commit"`
+	Date        string `json:"date"`
+	IsAnnotated bool   `json:"is_annotated"`
 }
 
 // Command execution
@@ -128,76 +154,32 @@ func GetBranchName(repoPath string) string {
 	return "unknown"
 }
 
-func GetAheadBehindCount(repoPath string) (ahead, behind int) {
-	// Use git status -sb which reliably shows ahead/behind even without explicit upstream
-	cmd := exec.Command("git", "status", "-sb")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
+// GetStatus reads branch, ahead/behind and change counts from a single
+// porcelain v2 status call
+func GetStatus(repoPath string) Status {
+	branch, changes, err := GetStatusEntries(repoPath)
 	if err != nil {
-		return 0, 0
+		return Status{Branch: "unknown"}
 	}
-
-	// Parse first line: ## branch...origin/branch [ahead N, behind M]
-	firstLine := strings.Split(string(output), "\n")[0]
-
-	// Look for [ahead N] or [behind N] or [ahead N, behind M]
-	if idx := strings.Index(firstLine, "["); idx != -1 {
-		if endIdx := strings.Index(firstLine[idx:], "]"); endIdx != -1 {
-			trackInfo := firstLine[idx+1 : idx+endIdx]
-			// Parse "ahead N" and/or "behind M"
-			if strings.Contains(trackInfo, "ahead") {
-				fmt.Sscanf(trackInfo, "ahead %d", &ahead)
-			}
-			if strings.Contains(trackInfo, "behind") {
-				if strings.Contains(trackInfo, "ahead") {
-					// Format: "ahead N, behind M"
-					parts := strings.Split(trackInfo, ", ")
-					for _, part := range parts {
-						if strings.HasPrefix(part, "behind") {
-							fmt.Sscanf(part, "behind %d", &behind)
-						}
-					}
-				} else {
-					fmt.Sscanf(trackInfo, "behind %d", &behind)
-				}
-			}
-		}
-	}
-
-	return ahead, behind
+	return newStatus(branch, changes)
 }
 
-func GetStatus(repoPath string) Status {
-	status := Status{Branch: GetBranchName(repoPath)}
-	status.Ahead, status.Behind = GetAheadBehindCount(repoPath)
-
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return status
+// newStatus condenses parsed status entries into branch and change counts
+func newStatus(branch BranchInfo, changes []Change) Status {
+	status := Status{Branch: branch.Head}
+	if branch.Head == "(detached)" {
+		status.Branch = "HEAD"
 	}
+	status.Ahead, status.Behind = branch.Ahead, branch.Behind
+	status.Clean = len(changes) == 0
 
-	statusText := strings.TrimSpace(string(output))
-	status.Clean = statusText == ""
-
-	if !status.Clean {
-		lines := strings.Split(statusText, "\n")
-		for _, line := range lines {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			if len(line) >= 2 {
-				stagedStatus := line[0]
-				unstagedStatus := line[1]
-
-				if stagedStatus != ' ' && stagedStatus != '?' {
-					status.StagedFiles++
-				}
-				if unstagedStatus != ' ' {
-					status.UnstagedFiles++
-				}
-			}
+	// Unmerged paths count as unstaged, matching the file list
+	for _, c := range changes {
+		if c.IsStaged() {
+			status.StagedFiles++
+		}
+		if c.IsConflict() || c.HasWorktreeChanges() {
+			status.UnstagedFiles++
 		}
 	}
 
@@ -205,30 +187,7 @@ func GetStatus(repoPath string) Status {
 }
 
 func GetChanges(repoPath string) []Change {
-	var changes []Change
-
-	cmd := exec.Command("git", "status", "--porcelain")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return changes
-	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.TrimSpace(line) == "" || len(line) < 3 {
-			continue
-		}
-
-		status := line[:2]
-		file := strings.TrimSpace(line[3:])
-
-		changes = append(changes, Change{
-			File:   file,
-			Status: status,
-		})
-	}
-
+	_, changes, _ := GetStatusEntries(repoPath)
 	return changes
 }
 
@@ -355,34 +314,6 @@ func GetCommitLog(repoPath string, count int) []Commit {
 	return commits
 }
 
-func GetReflog(repoPath string, count int) []Commit {
-	var commits []Commit
-
-	cmd := exec.Command("git", "reflog", fmt.Sprintf("-%d", count), "--pretty=format:%h|%s|%ar")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return commits
-	}
-
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "|", 3)
-		if len(parts) >= 3 {
-			commits = append(commits, Commit{
-				Hash:    parts[0],
-				Message: parts[1],
-				Date:    parts[2],
-			})
-		}
-	}
-
-	return commits
-}
-
 func GetCurrentCommitHash(repoPath string) string {
 	cmd := exec.Command("git", "rev-parse", "--short", "HEAD")
 	cmd.Dir = repoPath
@@ -396,16 +327,8 @@ func GetCurrentCommitHash(repoPath string) string {
 // Staging functions
 
 func IsFileStaged(repoPath, filePath string) bool {
-	cmd := exec.Command("git", "diff", "--cached", "--name-only")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return false
-	}
-
-	stagedFiles := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, f := range stagedFiles {
-		if strings.TrimSpace(f) == filePath {
+	for _, f := range GetStagedFiles(repoPath) {
+		if f == filePath {
 			return true
 		}
 	}
@@ -413,18 +336,24 @@ func IsFileStaged(repoPath, filePath string) bool {
 }
 
 func GetStagedFiles(repoPath string) []string {
-	cmd := exec.Command("git", "diff", "--cached", "--name-only")
+	cmd := exec.Command("git", "diff", "--cached", "--name-only", "-z")
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
 		return nil
 	}
+	return splitNul(string(output))
+}
 
-	text := strings.TrimSpace(string(output))
-	if text == "" {
-		return nil
+// splitNul splits -z output into paths, dropping the trailing empty entry
+func splitNul(output string) []string {
+	var paths []string
+	for _, p := range strings.Split(output, "\x00") {
+		if p != "" {
+			paths = append(paths, p)
+		}
 	}
-	return strings.Split(text, "\n")
+	return paths
 }
 
 func GetStagedDiff(repoPath string) string {
@@ -434,109 +363,27 @@ func GetStagedDiff(repoPath string) string {
 	return string(output)
 }
 
-// Diff functions
-
-func GetFileDiff(repoPath, filePath string, staged bool) string {
-	var cmd *exec.Cmd
-	if staged {
-		cmd = exec.Command("git", "diff", "--cached", filePath)
-	} else {
-		cmd = exec.Command("git", "diff", filePath)
-	}
-	cmd.Dir = repoPath
-	output, _ := cmd.Output()
-	return string(output)
-}
-
 // Conflict functions
 
 func GetConflictFiles(repoPath string) []string {
-	cmd := exec.Command("git", "diff", "--name-only", "--diff-filter=U")
+	cmd := exec.Command("git", "diff", "--name-only", "--diff-filter=U", "-z")
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
 		return nil
 	}
-
-	text := strings.TrimSpace(string(output))
-	if text == "" {
-		return nil
-	}
-	return strings.Split(text, "\n")
+	return splitNul(string(output))
 }
 
 // Comparison functions
 
-func GetBranchComparison(repoPath, sourceBranch, targetBranch string) BranchComparison {
-	comparison := BranchComparison{
-		SourceBranch: sourceBranch,
-		TargetBranch: targetBranch,
-	}
-
-	// Ahead commits
-	cmd := exec.Command("git", "log", "--pretty=format:%h|%s|%an|%ar", targetBranch+"..HEAD")
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err == nil {
-		lines := strings.Split(string(output), "\n")
-		for _, line := range lines {
-			if line == "" {
-				continue
-			}
-			parts := strings.SplitN(line, "|", 4)
-			if len(parts) >= 4 {
-				comparison.AheadCommits = append(comparison.AheadCommits, Commit{
-					Hash:    parts[0],
-					Message: parts[1],
-					Author:  parts[2],
-					Date:    parts[3],
-				})
-			}
-		}
-	}
-
-	// Behind commits
-	cmd = exec.Command("git", "log", "--pretty=format:%h|%s|%an|%ar", "HEAD.."+targetBranch)
-	cmd.Dir = repoPath
-	output, err = cmd.Output()
-	if err == nil {
-		lines := strings.Split(string(output), "\n")
-		for _, line := range lines {
-			if line == "" {
-				continue
-			}
-			parts := strings.SplitN(line, "|", 4)
-			if len(parts) >= 4 {
-				comparison.BehindCommits = append(comparison.BehindCommits, Commit{
-					Hash:    parts[0],
-					Message: parts[1],
-					Author:  parts[2],
-					Date:    parts[3],
-				})
-			}
-		}
-	}
-
-	// Differing files
-	cmd = exec.Command("git", "diff", "--name-only", targetBranch+"...HEAD")
-	cmd.Dir = repoPath
-	output, err = cmd.Output()
-	if err == nil {
-		text := strings.TrimSpace(string(output))
-		if text != "" {
-			comparison.DifferingFiles = strings.Split(text, "\n")
-		}
-	}
-
-	return comparison
-}
-
 // Stash functions
 
 func GetStashList(repoPath string) []Stash {
 	var stashes []Stash
 
-	cmd := exec.Command("git", "stash", "list", "--format=%gd|%s|%ar")
+	// %gs is the reflog subject, which a rename replaces
+	cmd := exec.Command("git", "stash", "list", "--format=%gd%x1f%gs%x1f%ar")
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
@@ -548,12 +395,13 @@ func GetStashList(repoPath string) []Stash {
 		if line == "" {
 			continue
 		}
-		parts := strings.SplitN(line, "|", 3)
+		parts := strings.SplitN(line, "\x1f", 3)
 		if len(parts) >= 3 {
 			stashes = append(stashes, Stash{
 				Index:   i,
 				Message: parts[1],
 				Date:    parts[2],
+				Branch:  stashBranch(parts[1]),
 			})
 		}
 	}
@@ -561,15 +409,109 @@ func GetStashList(repoPath string) []Stash {
 	return stashes
 }
 
-func StashPush(repoPath, message string) error {
-	var args []string
-	if message != "" {
-		args = []string{"stash", "push", "-m", message}
-	} else {
-		args = []string{"stash", "push"}
+// stashBranch reads the branch out of a stash subject: "WIP on main: 1a2b3c4
+// Fix typo" or "On main: message". Detached stashes say "(no branch)".
+func stashBranch(subject string) string {
+	for _, prefix := range []string{"WIP on ", "On "} {
+		if rest, ok := strings.CutPrefix(subject, prefix); ok {
+			if branch, _, ok := strings.Cut(rest, ": "); ok && branch != "(no branch)" {
+				return branch
+			}
+		}
 	}
-	_, err := Execute(repoPath, args...)
-	return err
+	return ""
+}
+
+// StashOptions are the flags of `git stash push`
+type StashOptions struct {
+	Message          string
+	IncludeUntracked bool     // -u: stash untracked files too
+	KeepIndex        bool     // --keep-index: staged changes also stay in the working tree
+	Staged           bool     // --staged: stash only staged changes
+	Paths            []string // only stash changes to these paths
+}
+
+func StashPush(repoPath string, opts StashOptions) error {
+	if opts.Staged && opts.IncludeUntracked {
+		return fmt.Errorf("--staged can't be combined with --include-untracked")
+	}
+
+	args := []string{"stash", "push"}
+	if opts.Message != "" {
+		args = append(args, "-m", opts.Message)
+	}
+	if opts.IncludeUntracked {
+		args = append(args, "--include-untracked")
+	}
+	if opts.KeepIndex {
+		args = append(args, "--keep-index")
+	}
+	if opts.Staged {
+		args = append(args, "--staged")
+	}
+	if len(opts.Paths) > 0 {
+		args = append(args, "--")
+		args = append(args, opts.Paths...)
+	}
+
+	output, err := Execute(repoPath, args...)
+	if err != nil {
+		return fmt.Errorf("%s", firstLine(output))
+	}
+	// Nothing to stash still exits 0
+	if strings.Contains(string(output), "No local changes to save") {
+		return fmt.Errorf("no local changes to save")
+	}
+	return nil
+}
+
+// RenameStash changes the message of stash@{index}, keeping the branch
+// prefix. Git can't rename a stash, so it and the newer stashes are dropped
+// and stored again in their old order; checkpoint first so a failure
+// halfway can be undone.
+func RenameStash(repoPath string, index int, message string) error {
+	stashes, err := stashHashes(repoPath)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(stashes) {
+		return fmt.Errorf("no stash@{%d}", index)
+	}
+
+	branch := stashBranch(stashes[index].Message)
+	if branch == "" {
+		branch = "(no branch)"
+	}
+	stashes[index].Message = fmt.Sprintf("On %s: %s", branch, message)
+
+	for i := 0; i <= index; i++ {
+		if output, err := Execute(repoPath, "stash", "drop", "-q", "stash@{0}"); err != nil {
+			return fmt.Errorf("%s", firstLine(output))
+		}
+	}
+	for i := index; i >= 0; i-- {
+		if output, err := Execute(repoPath, "stash", "store", "-m", stashes[i].Message, stashes[i].Hash); err != nil {
+			return fmt.Errorf("%s", firstLine(output))
+		}
+	}
+	return nil
+}
+
+// StashBranch creates branch name at the commit stash@{index} was made on,
+// checks it out and pops the stash there
+func StashBranch(repoPath, name string, index int) error {
+	output, err := Execute(repoPath, "stash", "branch", name, fmt.Sprintf("stash@{%d}", index))
+	if err != nil {
+		return fmt.Errorf("%s", firstLine(output))
+	}
+	return nil
+}
+
+// firstLine is the first line of git's output, without "error: "/"fatal: "
+func firstLine(output []byte) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+	line = strings.TrimPrefix(line, "error: ")
+	return strings.TrimPrefix(line, "fatal: ")
 }
 
 func StashPop(repoPath string, index int) error {
@@ -588,7 +530,7 @@ func StashDrop(repoPath string, index int) error {
 }
 
 func StashShow(repoPath string, index int) string {
-	cmd := exec.Command("git", "stash", "show", "-p", fmt.Sprintf("stash@{%d}", index))
+	cmd := exec.Command("git", "stash", "show", "-p", "--include-untracked", fmt.Sprintf("stash@{%d}", index))
 	cmd.Dir = repoPath
 	output, _ := cmd.Output()
 	return string(output)
@@ -599,8 +541,8 @@ func StashShow(repoPath string, index int) string {
 func GetTags(repoPath string) []Tag {
 	var tags []Tag
 
-	// Get all tags with their details
-	cmd := exec.Command("git", "tag", "-l", "--format=%(refname:short)|%(objecttype)|%(creatordate:relative)|%(*objectname:short)%(objectname:short)")
+	// Get all tags with their details; annotated tags are peeled to their commit
+	cmd := exec.Command("git", "tag", "-l", "--format=%(refname:short)|%(objecttype)|%(creatordate:relative)|%(if)%(*objectname)%(then)%(*objectname:short)%(else)%(objectname:short)%(end)")
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
@@ -664,19 +606,52 @@ func PushAllTags(repoPath string) error {
 
 // Cherry-pick and Revert functions
 
+// CherryPickOptions are the flags of a cherry-pick
+type CherryPickOptions struct {
+	RecordOrigin bool // -x: note the picked commit in the message
+	NoCommit     bool // -n: apply the changes to the index without committing
+	Mainline     int  // -m: parent of a merge commit to diff against; 0 for none
+}
+
 func CherryPick(repoPath, commitHash string) error {
-	_, err := Execute(repoPath, "cherry-pick", commitHash)
-	return err
+	return CherryPickCommits(repoPath, []string{commitHash}, CherryPickOptions{})
+}
+
+// CherryPickCommits applies commits onto HEAD in the order given. A conflict
+// leaves the cherry-pick in progress for ContinueOperation.
+func CherryPickCommits(repoPath string, hashes []string, opts CherryPickOptions) error {
+	args := []string{"cherry-pick"}
+	if opts.RecordOrigin {
+		args = append(args, "-x")
+	}
+	if opts.NoCommit {
+		args = append(args, "--no-commit")
+	}
+	if opts.Mainline > 0 {
+		args = append(args, "-m", strconv.Itoa(opts.Mainline))
+	}
+	output, err := Execute(repoPath, append(args, hashes...)...)
+	if err != nil {
+		// Progress lines come first; report the line saying what went wrong
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		message := lines[0]
+		for _, line := range lines {
+			if strings.HasPrefix(line, "CONFLICT") || strings.HasPrefix(line, "error:") || strings.HasPrefix(line, "fatal:") {
+				message = line
+				break
+			}
+		}
+		return fmt.Errorf("%s", message)
+	}
+	return nil
 }
 
 func CherryPickAbort(repoPath string) error {
-	_, err := Execute(repoPath, "cherry-pick", "--abort")
-	return err
+	return AbortOperation(repoPath, OpCherryPick)
 }
 
 func CherryPickContinue(repoPath string) error {
-	_, err := Execute(repoPath, "cherry-pick", "--continue")
-	return err
+	return ContinueOperation(repoPath, OpCherryPick)
 }
 
 func RevertCommit(repoPath, commitHash string) error {
@@ -685,32 +660,36 @@ func RevertCommit(repoPath, commitHash string) error {
 }
 
 func RevertAbort(repoPath string) error {
-	_, err := Execute(repoPath, "revert", "--abort")
-	return err
+	return AbortOperation(repoPath, OpRevert)
 }
 
 // Clean functions
 
+// CleanDryRun lists the untracked, non-ignored paths `git clean -d` would
+// remove; untracked directories are listed once, with a trailing slash
 func CleanDryRun(repoPath string) ([]string, error) {
-	output, err := Execute(repoPath, "clean", "-n", "-d")
+	// NUL-separated so names git would C-quote come back as they are on disk
+	cmd := exec.Command("git", "ls-files", "-z", "--others", "--exclude-standard", "--directory")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("git ls-files failed: %w", err)
 	}
-
-	var files []string
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimPrefix(line, "Would remove ")
-		if line != "" {
-			files = append(files, line)
-		}
-	}
-	return files, nil
+	return splitNul(string(output)), nil
 }
 
-func CleanForce(repoPath string) error {
-	_, err := Execute(repoPath, "clean", "-f", "-d")
-	return err
+// CleanForce removes exactly paths, as listed by CleanDryRun. Files created
+// since the dry run are left alone, so nothing unlisted is lost.
+func CleanForce(repoPath string, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	args := append([]string{"--literal-pathspecs", "clean", "-f", "-d", "--"}, paths...)
+	output, err := Execute(repoPath, args...)
+	if err != nil {
+		return fmt.Errorf("%s", firstLine(output))
+	}
+	return nil
 }
 
 // Clone and Init functions
@@ -742,34 +721,75 @@ type CommitDetail struct {
 	Deletions  int
 }
 
-func GetCommitLog2(repoPath string, count int, search string) []Commit {
-	var commits []Commit
-	args := []string{"log", fmt.Sprintf("-%d", count), "--pretty=format:%h|%s|%an|%ar"}
-	if search != "" {
-		args = append(args, "--grep="+search)
-	}
+// logFormat separates fields with \x1f so subjects containing "|" parse cleanly
+const logFormat = "--pretty=format:%h%x1f%p%x1f%D%x1f%s%x1f%an%x1f%ar"
+
+// GetCommitPage returns up to count commits of the query after skipping the
+// first skip, so long histories can be loaded a page at a time. A short page
+// means the end of history was reached.
+func GetCommitPage(repoPath string, query LogQuery, skip, count int) []Commit {
+	args := []string{"log", fmt.Sprintf("--skip=%d", skip), fmt.Sprintf("-%d", count),
+		"--topo-order", "--decorate=full", logFormat}
+	args = append(args, query.args()...)
 
 	cmd := exec.Command("git", args...)
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
-		return commits
+		return nil
 	}
+	return parseCommitLog(string(output))
+}
 
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
+// ResolveRev returns the full hash of the commit rev points to, or "" if it
+// doesn't exist
+func ResolveRev(repoPath, rev string) string {
+	hash, _ := revParse(repoPath, rev+"^{commit}")
+	return hash
+}
+
+// revParse returns the full hash of any object rev names, such as a tree
+// with "<rev>^{tree}"
+func revParse(repoPath, rev string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", rev)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("cannot resolve %s", rev)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ShortHash abbreviates a full hash to the 7 characters used for display
+func ShortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
+
+// parseCommitLog parses output produced with logFormat
+func parseCommitLog(output string) []Commit {
+	var commits []Commit
+	for _, line := range strings.Split(output, "\n") {
 		if line == "" {
 			continue
 		}
-		parts := strings.SplitN(line, "|", 4)
-		if len(parts) >= 4 {
-			commits = append(commits, Commit{
-				Hash:    parts[0],
-				Message: parts[1],
-				Author:  parts[2],
-				Date:    parts[3],
-			})
+		parts := strings.SplitN(line, "\x1f", 6)
+		if len(parts) < 6 {
+			continue
+		}
+		commit := Commit{
+			Hash:    parts[0],
+			Parents: strings.Fields(parts[1]),
+			Message: parts[3],
+			Author:  parts[4],
+			Date:    parts[5],
+		}
+		if parts[2] != "" {
+			commit.Refs = strings.Split(parts[2], ", ")
 		}
+		commits = append(commits, commit)
 	}
 	return commits
 }
@@ -778,28 +798,27 @@ func GetCommitDetail(repoPath, hash string) CommitDetail {
 	detail := CommitDetail{Hash: hash}
 
 	// Get commit info
-	cmd := exec.Command("git", "show", hash, "--pretty=format:%H|%s|%b|%an|%ae|%ar", "--stat")
+	// NUL-separated so multi-line bodies stay intact; the --stat follows the last field
+	cmd := exec.Command("git", "show", hash, "--pretty=format:%H%x00%s%x00%an%x00%ae%x00%ar%x00%b%x00", "--stat")
 	cmd.Dir = repoPath
 	output, err := cmd.Output()
 	if err != nil {
 		return detail
 	}
 
-	lines := strings.Split(string(output), "\n")
-	if len(lines) > 0 {
-		parts := strings.SplitN(lines[0], "|", 6)
-		if len(parts) >= 6 {
-			detail.Hash = parts[0]
-			detail.Message = parts[1]
-			detail.Body = parts[2]
-			detail.Author = parts[3]
-			detail.Email = parts[4]
-			detail.Date = parts[5]
-		}
+	parts := strings.SplitN(string(output), "\x00", 7)
+	if len(parts) < 7 {
+		return detail
 	}
+	detail.Hash = parts[0]
+	detail.Message = parts[1]
+	detail.Author = parts[2]
+	detail.Email = parts[3]
+	detail.Date = parts[4]
+	detail.Body = strings.TrimSpace(parts[5])
 
 	// Parse file stats
-	for _, line := range lines[1:] {
+	for _, line := range strings.Split(parts[6], "\n") {
 		line = strings.TrimSpace(line)
 		if line == "" || strings.HasPrefix(line, "---") {
 			continue
@@ -825,130 +844,3 @@ func GetCommitDiff(repoPath, hash string) string {
 	output, _ := cmd.Output()
 	return string(output)
 }
-
-// Interactive Rebase functions
-
-func ExecuteRebase(repoPath string, commits []RebaseCommit) error {
-	if len(commits) == 0 {
-		return fmt.Errorf("no commits to rebase")
-	}
-
-	// Build rebase todo content (oldest first, so reverse the slice)
-	var todoLines []string
-	for i := len(commits) - 1; i >= 0; i-- {
-		commit := commits[i]
-		action := commit.Action
-		if action == "" {
-			action = "pick"
-		}
-		todoLines = append(todoLines, fmt.Sprintf("%s %s %s", action, commit.Hash, commit.Message))
-	}
-	todoContent := strings.Join(todoLines, "\n") + "\n"
-
-	// Write todo to temp file
-	tmpFile, err := os.CreateTemp("", "gitty-rebase-*.txt")
-	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
-	}
-	tmpPath := tmpFile.Name()
-	defer os.Remove(tmpPath)
-
-	if _, err := tmpFile.WriteString(todoContent); err != nil {
-		tmpFile.Close()
-		return fmt.Errorf("failed to write rebase todo: %w", err)
-	}
-	tmpFile.Close()
-
-	// Create editor script that copies our todo file
-	editorScript := fmt.Sprintf("cp %s \"$1\"", tmpPath)
-
-	// Run git rebase with our custom editor
-	count := len(commits)
-	cmd := exec.Command("git", "rebase", "-i", fmt.Sprintf("HEAD~%d", count))
-	cmd.Dir = repoPath
-	cmd.Env = append(os.Environ(), "GIT_SEQUENCE_EDITOR=sh -c '"+editorScript+"'")
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("rebase failed: %s", string(output))
-	}
-
-	return nil
-}
-
-func AbortRebase(repoPath string) error {
-	_, err := Execute(repoPath, "rebase", "--abort")
-	return err
-}
-
-func ContinueRebase(repoPath string) error {
-	_, err := Execute(repoPath, "rebase", "--continue")
-	return err
-}
-
-func IsRebaseInProgress(repoPath string) bool {
-	rebaseMerge := filepath.Join(repoPath, ".git", "rebase-merge")
-	rebaseApply := filepath.Join(repoPath, ".git", "rebase-apply")
-	_, err1 := os.Stat(rebaseMerge)
-	_, err2 := os.Stat(rebaseApply)
-	return err1 == nil || err2 == nil
-}
-
-// Blame functions
-
-type BlameLine struct {
-	Hash    string
-	Author  string
-	Date    string
-	LineNum int
-	Content string
-}
-
-func GetBlame(repoPath, filePath string) []BlameLine {
-	var lines []BlameLine
-
-	cmd := exec.Command("git", "blame", "--porcelain", filePath)
-	cmd.Dir = repoPath
-	output, err := cmd.Output()
-	if err != nil {
-		return lines
-	}
-
-	rawLines := strings.Split(string(output), "\n")
-	var currentHash, currentAuthor, currentDate string
-	lineNum := 0
-
-	for i := 0; i < len(rawLines); i++ {
-		line := rawLines[i]
-		if line == "" {
-			continue
-		}
-
-		// Hash line: starts with 40-char hash
-		if len(line) >= 40 && !strings.HasPrefix(line, "\t") {
-			parts := strings.Fields(line)
-			if len(parts) >= 1 {
-				currentHash = parts[0][:7] // short hash
-			}
-		} else if strings.HasPrefix(line, "author ") {
-			currentAuthor = strings.TrimPrefix(line, "author ")
-		} else if strings.HasPrefix(line, "author-time ") {
-			ts := strings.TrimPrefix(line, "author-time ")
-			if timestamp, err := strconv.ParseInt(ts, 10, 64); err == nil {
-				t := time.Unix(timestamp, 0)
-				currentDate = t.Format("2006-01-02")
-			}
-		} else if strings.HasPrefix(line, "\t") {
-			lineNum++
-			lines = append(lines, BlameLine{
-				Hash:    currentHash,
-				Author:  currentAuthor,
-				Date:    currentDate,
-				LineNum: lineNum,
-				Content: strings.TrimPrefix(line, "\t"),
-			})
-		}
-	}
-
-	return lines
-}
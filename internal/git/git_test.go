@@ -0,0 +1,76 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestRepo creates an empty repository isolated from the user's git
+// config. Author, committer and dates are fixed so hashes are reproducible.
+func newTestRepo(t *testing.T) string {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", "/dev/null")
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_AUTHOR_NAME", "Gitty Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_AUTHOR_DATE", "2024-01-01T12:00:00Z")
+	t.Setenv("GIT_COMMITTER_NAME", "Gitty Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_DATE", "2024-01-01T12:00:00Z")
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "--quiet", "--initial-branch=main")
+	return dir
+}
+
+// runGit runs a git command in dir and fails the test if it fails
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+// writeFile writes content to a file relative to the repository root
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestGetStatusConflicts checks that unmerged paths are counted the way the
+// file list shows them: unstaged, never staged
+func TestGetStatusConflicts(t *testing.T) {
+	repo := newTestRepo(t)
+	writeFile(t, repo, "a.txt", "base\n")
+	writeFile(t, repo, "b.txt", "base\n")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "--quiet", "-m", "base")
+	runGit(t, repo, "checkout", "--quiet", "-b", "other")
+	writeFile(t, repo, "a.txt", "other\n")
+	runGit(t, repo, "commit", "--quiet", "-am", "other")
+	runGit(t, repo, "checkout", "--quiet", "main")
+	writeFile(t, repo, "a.txt", "main\n")
+	runGit(t, repo, "commit", "--quiet", "-am", "main")
+
+	cmd := exec.Command("git", "merge", "other")
+	cmd.Dir = repo
+	if cmd.Run() == nil {
+		t.Fatal("expected the merge to conflict")
+	}
+	writeFile(t, repo, "b.txt", "staged\n")
+	runGit(t, repo, "add", "b.txt")
+
+	status := GetStatus(repo)
+	if status.StagedFiles != 1 || status.UnstagedFiles != 1 {
+		t.Errorf("got %d staged, %d unstaged; want 1 staged (b.txt), 1 unstaged (a.txt)", status.StagedFiles, status.UnstagedFiles)
+	}
+}
@@ -0,0 +1,440 @@
+package git
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// journalLimit is how many checkpoints the journal keeps; older ones and
+// their file backups are dropped
+const journalLimit = 50
+
+// JournalEntry is the repository state saved just before one of gitty's
+// destructive actions. Commits are only referenced by hash, so they last as
+// long as git keeps unreachable objects (gc.pruneExpire, two weeks by default).
+type JournalEntry struct {
+	ID        string            `json:"id"`
+	Time      time.Time         `json:"time"`
+	Action    string            `json:"action"`              // "discard main.go", "delete branch feature"
+	Head      string            `json:"head,omitempty"`      // commit HEAD pointed at
+	Branch    string            `json:"branch,omitempty"`    // branch HEAD was on; empty when detached
+	Branches  map[string]string `json:"branches,omitempty"`  // tip of every local branch
+	Worktree  string            `json:"worktree,omitempty"`  // `git stash create` commit of staged and unstaged changes
+	Untracked []string          `json:"untracked,omitempty"` // untracked paths copied into the backup directory
+	Stashes   []JournalStash    `json:"stashes,omitempty"`   // stash list, newest first
+	Undone    bool              `json:"undone,omitempty"`
+}
+
+// JournalStash is one stash as saved in a journal entry
+type JournalStash struct {
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+}
+
+// journalPaths returns the journal file and the directory holding file backups
+func journalPaths(repoPath string) (string, string, error) {
+	dir, err := gitPath(repoPath, "gitty")
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(dir, "journal.json"), filepath.Join(dir, "journal"), nil
+}
+
+// GetJournal returns the saved checkpoints, oldest first
+func GetJournal(repoPath string) ([]JournalEntry, error) {
+	path, _, err := journalPaths(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []JournalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("journal %s is corrupt: %w", path, err)
+	}
+	return entries, nil
+}
+
+func writeJournal(repoPath string, entries []JournalEntry) error {
+	path, backupDir, err := journalPaths(repoPath)
+	if err != nil {
+		return err
+	}
+	for len(entries) > journalLimit {
+		os.RemoveAll(filepath.Join(backupDir, entries[0].ID))
+		entries = entries[1:]
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LastUndoable returns the newest checkpoint that has not been undone
+func LastUndoable(repoPath string) (JournalEntry, bool) {
+	entries, err := GetJournal(repoPath)
+	if err != nil {
+		return JournalEntry{}, false
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].Undone {
+			return entries[i], true
+		}
+	}
+	return JournalEntry{}, false
+}
+
+// Checkpoint records HEAD, the branch tips, uncommitted changes and the stash
+// list before action runs. untracked lists paths (files or directories, as
+// listed by CleanDryRun) that the action will delete; they are copied
+// into the backup directory. Callers should not run the action when this
+// fails.
+func Checkpoint(repoPath, action string, untracked []string) (JournalEntry, error) {
+	now := time.Now()
+	entry := JournalEntry{
+		ID:     now.Format("20060102-150405.000000"),
+		Time:   now,
+		Action: action,
+		Branch: symbolicBranch(repoPath),
+	}
+	// Unborn branches have no HEAD yet
+	entry.Head, _ = revParse(repoPath, "HEAD")
+
+	refs, err := branchTips(repoPath)
+	if err != nil {
+		return entry, fmt.Errorf("checkpoint failed: %w", err)
+	}
+	entry.Branches = refs
+
+	if entry.Head != "" {
+		// Empty when there is nothing to save
+		output, err := Execute(repoPath, "stash", "create")
+		if err != nil {
+			return entry, fmt.Errorf("checkpoint failed: %s", strings.TrimSpace(string(output)))
+		}
+		entry.Worktree = strings.TrimSpace(string(output))
+	}
+
+	stashes, err := stashHashes(repoPath)
+	if err != nil {
+		return entry, fmt.Errorf("checkpoint failed: %w", err)
+	}
+	entry.Stashes = stashes
+
+	if len(untracked) > 0 {
+		_, backupDir, err := journalPaths(repoPath)
+		if err != nil {
+			return entry, err
+		}
+		dest := filepath.Join(backupDir, entry.ID)
+		for _, path := range untracked {
+			path = strings.TrimSuffix(path, "/")
+			if err := copyTree(filepath.Join(repoPath, path), filepath.Join(dest, path)); err != nil {
+				os.RemoveAll(dest)
+				return entry, fmt.Errorf("checkpoint failed: backing up %s: %w", path, err)
+			}
+			entry.Untracked = append(entry.Untracked, path)
+		}
+	}
+
+	entries, err := GetJournal(repoPath)
+	if err != nil {
+		return entry, err
+	}
+	if err := writeJournal(repoPath, append(entries, entry)); err != nil {
+		return entry, fmt.Errorf("checkpoint failed: %w", err)
+	}
+	return entry, nil
+}
+
+// DropCheckpoint removes entry from the journal when its action failed
+// without changing anything, so undo doesn't replay an action that never
+// happened. A checkpoint is kept while the repository differs from it: an
+// action that failed halfway can still be undone. dropped reports which
+// happened.
+func DropCheckpoint(repoPath string, entry JournalEntry) (dropped bool, err error) {
+	if !matchesCheckpoint(repoPath, entry) {
+		return false, nil
+	}
+	entries, err := GetJournal(repoPath)
+	if err != nil {
+		return false, err
+	}
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.ID != entry.ID {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == len(entries) {
+		return false, nil
+	}
+	if err := writeJournal(repoPath, kept); err != nil {
+		return false, err
+	}
+	if _, backupDir, err := journalPaths(repoPath); err == nil {
+		os.RemoveAll(filepath.Join(backupDir, entry.ID))
+	}
+	return true, nil
+}
+
+// matchesCheckpoint reports whether HEAD, the branch tips, the stash list,
+// the staged and unstaged changes and the backed up untracked files are
+// still as entry saved them
+func matchesCheckpoint(repoPath string, entry JournalEntry) bool {
+	head, _ := revParse(repoPath, "HEAD")
+	if head != entry.Head || symbolicBranch(repoPath) != entry.Branch {
+		return false
+	}
+	tips, err := branchTips(repoPath)
+	if err != nil || !maps.Equal(tips, entry.Branches) {
+		return false
+	}
+	stashes, err := stashHashes(repoPath)
+	if err != nil || !slices.Equal(stashes, entry.Stashes) {
+		return false
+	}
+	for _, path := range entry.Untracked {
+		if _, err := os.Lstat(filepath.Join(repoPath, path)); err != nil {
+			return false
+		}
+	}
+
+	if entry.Head == "" {
+		return true
+	}
+	output, err := Execute(repoPath, "stash", "create")
+	if err != nil {
+		return false
+	}
+	// Stash commits differ by timestamp; compare the working tree and index
+	worktree := strings.TrimSpace(string(output))
+	if worktree == "" || entry.Worktree == "" {
+		return worktree == entry.Worktree
+	}
+	for _, rev := range []string{"^{tree}", "^2^{tree}"} {
+		now, _ := revParse(repoPath, worktree+rev)
+		then, _ := revParse(repoPath, entry.Worktree+rev)
+		if now != then {
+			return false
+		}
+	}
+	return true
+}
+
+// UndoLast restores the state saved by the newest checkpoint that has not
+// been undone: branch tips (deleted branches come back), HEAD, staged and
+// unstaged changes, backed up untracked files and dropped stashes. Branches
+// and stashes created since are kept. The state being replaced is
+// checkpointed first, so undoing again redoes the action.
+func UndoLast(repoPath string) (JournalEntry, error) {
+	entry, ok := LastUndoable(repoPath)
+	if !ok {
+		return entry, errors.New("nothing to undo")
+	}
+	if op := GetOperation(repoPath); op != OpNone {
+		return entry, fmt.Errorf("finish or abort the %s first", op)
+	}
+	branch := symbolicBranch(repoPath)
+	if branch != entry.Branch {
+		if entry.Branch == "" {
+			return entry, fmt.Errorf("HEAD was detached at %s; check it out first", ShortHash(entry.Head))
+		}
+		return entry, fmt.Errorf("switch back to %s first", entry.Branch)
+	}
+
+	// Current versions of the files about to be restored go into the undo's
+	// own checkpoint
+	var existing []string
+	for _, path := range entry.Untracked {
+		if _, err := os.Lstat(filepath.Join(repoPath, path)); err == nil {
+			existing = append(existing, path)
+		}
+	}
+	if _, err := Checkpoint(repoPath, "undo "+entry.Action, existing); err != nil {
+		return entry, err
+	}
+
+	current, _ := branchTips(repoPath)
+	for name, hash := range entry.Branches {
+		if name == branch || current[name] == hash {
+			continue
+		}
+		if output, err := Execute(repoPath, "update-ref", "refs/heads/"+name, hash); err != nil {
+			return entry, fmt.Errorf("restoring %s: %s", name, strings.TrimSpace(string(output)))
+		}
+	}
+
+	if entry.Head != "" {
+		if output, err := Execute(repoPath, "reset", "--hard", entry.Head); err != nil {
+			return entry, fmt.Errorf("restoring HEAD: %s", strings.TrimSpace(string(output)))
+		}
+	}
+	if entry.Worktree != "" {
+		if _, err := Execute(repoPath, "stash", "apply", "--index", entry.Worktree); err != nil {
+			if output, err := Execute(repoPath, "stash", "apply", entry.Worktree); err != nil {
+				return entry, fmt.Errorf("restoring changes: %s", strings.TrimSpace(string(output)))
+			}
+		}
+	}
+
+	if len(entry.Untracked) > 0 {
+		_, backupDir, err := journalPaths(repoPath)
+		if err != nil {
+			return entry, err
+		}
+		for _, path := range entry.Untracked {
+			if err := copyTree(filepath.Join(backupDir, entry.ID, path), filepath.Join(repoPath, path)); err != nil {
+				return entry, fmt.Errorf("restoring %s: %w", path, err)
+			}
+		}
+	}
+
+	// Oldest first, so restored stashes keep their order
+	present, _ := stashHashes(repoPath)
+	have := make(map[string]bool, len(present))
+	for _, s := range present {
+		have[s.Hash] = true
+	}
+	for i := len(entry.Stashes) - 1; i >= 0; i-- {
+		s := entry.Stashes[i]
+		if have[s.Hash] {
+			continue
+		}
+		if output, err := Execute(repoPath, "stash", "store", "-m", s.Message, s.Hash); err != nil {
+			return entry, fmt.Errorf("restoring stash %q: %s", s.Message, strings.TrimSpace(string(output)))
+		}
+	}
+
+	entries, err := GetJournal(repoPath)
+	if err != nil {
+		return entry, err
+	}
+	for i := range entries {
+		if entries[i].ID == entry.ID {
+			entries[i].Undone = true
+		}
+	}
+	entry.Undone = true
+	return entry, writeJournal(repoPath, entries)
+}
+
+// symbolicBranch is the branch HEAD is on, or "" when detached
+func symbolicBranch(repoPath string) string {
+	cmd := exec.Command("git", "symbolic-ref", "-q", "--short", "HEAD")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func branchTips(repoPath string) (map[string]string, error) {
+	cmd := exec.Command("git", "for-each-ref", "--format=%(refname:short)%09%(objectname)", "refs/heads")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	tips := make(map[string]string)
+	for _, line := range strings.Split(string(output), "\n") {
+		if name, hash, ok := strings.Cut(line, "\t"); ok {
+			tips[name] = hash
+		}
+	}
+	return tips, nil
+}
+
+func stashHashes(repoPath string) ([]JournalStash, error) {
+	cmd := exec.Command("git", "stash", "list", "--format=%H%x1f%gs")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	var stashes []JournalStash
+	for _, line := range strings.Split(string(output), "\n") {
+		if hash, message, ok := strings.Cut(line, "\x1f"); ok {
+			stashes = append(stashes, JournalStash{Hash: hash, Message: message})
+		}
+	}
+	return stashes, nil
+}
+
+// copyTree copies a file, symlink or directory, keeping modes
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, mode fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,108 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestFailedActionLeavesNoCheckpoint deletes an unmerged branch with -d,
+// which git refuses, and checks the journal is as it was before
+func TestFailedActionLeavesNoCheckpoint(t *testing.T) {
+	repo := newTestRepo(t)
+	writeFile(t, repo, "a.txt", "base\n")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "--quiet", "-m", "base")
+	runGit(t, repo, "checkout", "--quiet", "-b", "feature")
+	writeFile(t, repo, "a.txt", "feature\n")
+	runGit(t, repo, "commit", "--quiet", "-am", "unmerged work")
+	runGit(t, repo, "checkout", "--quiet", "main")
+
+	earlier, err := Checkpoint(repo, "discard a.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint, err := Checkpoint(repo, "delete branch feature", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Execute(repo, "branch", "-d", "feature"); err == nil {
+		t.Fatal("expected git to refuse deleting an unmerged branch")
+	}
+	if dropped, err := DropCheckpoint(repo, checkpoint); err != nil || !dropped {
+		t.Fatalf("DropCheckpoint = %v, %v; want the checkpoint dropped", dropped, err)
+	}
+
+	last, ok := LastUndoable(repo)
+	if !ok || last.ID != earlier.ID {
+		t.Errorf("LastUndoable = %q, want the earlier %q", last.Action, earlier.Action)
+	}
+}
+
+// TestPartialActionKeepsCheckpoint checks that a checkpoint survives when the
+// repository changed, as after an action that failed halfway
+func TestPartialActionKeepsCheckpoint(t *testing.T) {
+	repo := newTestRepo(t)
+	writeFile(t, repo, "a.txt", "base\n")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "--quiet", "-m", "base")
+
+	checkpoint, err := Checkpoint(repo, "discard a.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo, "a.txt", "changed\n")
+
+	if dropped, err := DropCheckpoint(repo, checkpoint); err != nil || dropped {
+		t.Fatalf("DropCheckpoint = %v, %v; want the checkpoint kept", dropped, err)
+	}
+	if last, ok := LastUndoable(repo); !ok || last.ID != checkpoint.ID {
+		t.Errorf("LastUndoable = %q, want %q", last.Action, checkpoint.Action)
+	}
+}
+
+// TestCleanBacksUpQuotedNames cleans untracked files whose names git would
+// C-quote and checks a file created after the dry run survives
+func TestCleanBacksUpQuotedNames(t *testing.T) {
+	repo := newTestRepo(t)
+	writeFile(t, repo, "a.txt", "base\n")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "--quiet", "-m", "base")
+
+	writeFile(t, repo, "héllo.txt", "accent\n")
+	writeFile(t, repo, `a"b.txt`, "quote\n")
+	if err := os.MkdirAll(filepath.Join(repo, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo, "dir/nested.txt", "nested\n")
+
+	files, err := CleanDryRun(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`a"b.txt`, "dir/", "héllo.txt"}; !reflect.DeepEqual(files, want) {
+		t.Fatalf("CleanDryRun = %q, want %q", files, want)
+	}
+	if _, err := Checkpoint(repo, "clean", files); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, repo, "late.txt", "created after the dry run\n")
+	if err := CleanForce(repo, files); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "late.txt")); err != nil {
+		t.Errorf("late.txt was not backed up and should not be cleaned: %v", err)
+	}
+
+	if _, err := UndoLast(repo); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"héllo.txt", `a"b.txt`, "dir/nested.txt"} {
+		if _, err := os.Stat(filepath.Join(repo, name)); err != nil {
+			t.Errorf("%s not restored: %v", name, err)
+		}
+	}
+}
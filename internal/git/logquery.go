@@ -0,0 +1,208 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Merge filters for LogQuery.Merges
+const (
+	MergesOnly = "only"
+	MergesNone = "none"
+)
+
+// LogQuery selects the commits a paged history walks
+type LogQuery struct {
+	Rev         string   // starting revision; pin to a hash so pages don't shift as HEAD moves
+	Search      string   // --grep pattern
+	Author      string   // --author pattern
+	Committer   string   // --committer pattern
+	Since       string   // --since date, e.g. "2 weeks ago"
+	Until       string   // --until date
+	Paths       []string // pathspecs
+	Pickaxe     string   // -S: commits changing the number of occurrences
+	Regex       string   // -G: commits whose diff matches the regex
+	Merges      string   // MergesOnly, MergesNone or "" for both
+	FirstParent bool
+}
+
+// args builds the git log arguments for the query, revision and pathspecs last
+func (q LogQuery) args() []string {
+	var args []string
+	if q.Search != "" {
+		args = append(args, "--grep="+q.Search)
+	}
+	if q.Author != "" {
+		args = append(args, "--author="+q.Author)
+	}
+	if q.Committer != "" {
+		args = append(args, "--committer="+q.Committer)
+	}
+	if q.Since != "" {
+		args = append(args, "--since="+q.Since)
+	}
+	if q.Until != "" {
+		args = append(args, "--until="+q.Until)
+	}
+	if q.Pickaxe != "" {
+		args = append(args, "-S"+q.Pickaxe)
+	}
+	if q.Regex != "" {
+		args = append(args, "-G"+q.Regex)
+	}
+	switch q.Merges {
+	case MergesOnly:
+		args = append(args, "--merges")
+	case MergesNone:
+		args = append(args, "--no-merges")
+	}
+	if q.FirstParent {
+		args = append(args, "--first-parent")
+	}
+
+	rev := q.Rev
+	if rev == "" {
+		rev = "HEAD"
+	}
+	args = append(args, rev, "--")
+	return append(args, q.Paths...)
+}
+
+// Filtered reports whether anything beyond the starting revision narrows the
+// history, in which case commits no longer link up with their parents
+func (q LogQuery) Filtered() bool {
+	return q.Search != "" || q.String() != ""
+}
+
+// Equal compares two queries field by field
+func (q LogQuery) Equal(o LogQuery) bool {
+	return q.Rev == o.Rev && q.Search == o.Search && q.String() == o.String()
+}
+
+// String formats the filter fields (not Rev or Search) in the syntax
+// ParseLogFilter reads
+func (q LogQuery) String() string {
+	var parts []string
+	add := func(key, value string) {
+		if value != "" {
+			parts = append(parts, key+":"+quoteFilterValue(value))
+		}
+	}
+	add("author", q.Author)
+	add("committer", q.Committer)
+	add("since", q.Since)
+	add("until", q.Until)
+	for _, p := range q.Paths {
+		add("path", p)
+	}
+	add("S", q.Pickaxe)
+	add("G", q.Regex)
+	switch q.Merges {
+	case MergesOnly:
+		parts = append(parts, "merges")
+	case MergesNone:
+		parts = append(parts, "no-merges")
+	}
+	if q.FirstParent {
+		parts = append(parts, "first-parent")
+	}
+	return strings.Join(parts, " ")
+}
+
+func quoteFilterValue(value string) string {
+	if strings.ContainsFunc(value, unicode.IsSpace) || strings.Contains(value, `"`) {
+		return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+	}
+	return value
+}
+
+// ParseLogFilter parses a filter bar string such as
+//
+//	author:alice since:"2 weeks ago" path:src/ S:TODO no-merges
+//
+// Keys: author, committer, since, until, path (repeatable), S (pickaxe),
+// G (diff regex), plus the flags merges, no-merges and first-parent.
+func ParseLogFilter(input string) (LogQuery, error) {
+	var q LogQuery
+	tokens, err := splitFilterTokens(input)
+	if err != nil {
+		return q, err
+	}
+
+	for _, tok := range tokens {
+		key, value, hasValue := strings.Cut(tok, ":")
+		if !hasValue {
+			switch tok {
+			case "merges":
+				q.Merges = MergesOnly
+			case "no-merges":
+				q.Merges = MergesNone
+			case "first-parent":
+				q.FirstParent = true
+			default:
+				return q, fmt.Errorf("unknown filter %q (use key:value)", tok)
+			}
+			continue
+		}
+		if value == "" {
+			return q, fmt.Errorf("missing value for %s", key)
+		}
+
+		switch key {
+		case "author":
+			q.Author = value
+		case "committer":
+			q.Committer = value
+		case "since", "after":
+			q.Since = value
+		case "until", "before":
+			q.Until = value
+		case "path":
+			q.Paths = append(q.Paths, value)
+		case "S", "pickaxe":
+			q.Pickaxe = value
+		case "G", "regex":
+			q.Regex = value
+		default:
+			return q, fmt.Errorf("unknown filter key %q", key)
+		}
+	}
+	return q, nil
+}
+
+// splitFilterTokens splits on whitespace, keeping double-quoted runs together
+func splitFilterTokens(input string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inQuote, escaped, started := false, false, false
+
+	for _, r := range input {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && inQuote:
+			escaped = true
+		case r == '"':
+			inQuote = !inQuote
+			started = true
+		case unicode.IsSpace(r) && !inQuote:
+			if started {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if started {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens, nil
+}
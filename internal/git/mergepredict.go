@@ -0,0 +1,210 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// MergePrediction is the outcome of a dry-run merge or rebase. Predictions
+// only write objects (merge results and scratch commits), never the working
+// tree, index or refs.
+type MergePrediction struct {
+	Clean     bool                `json:"clean"`
+	Commit    string              `json:"commit,omitempty"` // rebase: the commit that would stop
+	Conflicts []PredictedConflict `json:"conflicts"`
+	Error     string              `json:"error,omitempty"` // prediction could not run
+}
+
+// PredictedConflict is one file that would conflict
+type PredictedConflict struct {
+	Path    string   `json:"path"`
+	Type    string   `json:"type"`    // git's conflict type, e.g. "contents", "modify/delete"
+	Message string   `json:"message"` // git's CONFLICT message
+	Hunks   []string `json:"hunks,omitempty"`
+}
+
+const (
+	conflictHunkContext = 3
+	maxConflictHunks    = 10
+)
+
+// PredictMerge reports whether merging target into source would conflict,
+// using `git merge-tree --write-tree` (git 2.38+)
+func PredictMerge(repoPath, source, target string) MergePrediction {
+	prediction, _ := mergeTree(repoPath, source, target)
+	return prediction
+}
+
+// PredictRebase replays an interactive rebase plan (newest first, as in
+// ExecuteRebase) onto its base and reports the first pick that would
+// conflict. Each pick is a merge-tree of the commit with a scratch commit
+// holding the result so far, parented on the pick's own parent so the
+// merge base is the same as git cherry-pick's. Exec lines are skipped.
+func PredictRebase(repoPath string, commits []RebaseCommit, opts RebaseOptions) MergePrediction {
+	if rebaseCount(commits) == 0 {
+		return MergePrediction{Clean: true, Conflicts: []PredictedConflict{}}
+	}
+
+	tree, err := revParse(repoPath, rebaseBase(commits, opts)+"^{tree}")
+	if err != nil {
+		return MergePrediction{Conflicts: []PredictedConflict{}, Error: err.Error()}
+	}
+
+	for i := len(commits) - 1; i >= 0; i-- {
+		commit := commits[i]
+		if commit.Action == "drop" || commit.Action == "exec" {
+			continue
+		}
+		parent, err := revParse(repoPath, commit.Hash+"^")
+		if err != nil {
+			return MergePrediction{Conflicts: []PredictedConflict{}, Commit: commit.Hash, Error: err.Error()}
+		}
+		scratch, err := scratchCommit(repoPath, tree, parent)
+		if err != nil {
+			return MergePrediction{Conflicts: []PredictedConflict{}, Commit: commit.Hash, Error: err.Error()}
+		}
+
+		prediction, result := mergeTree(repoPath, scratch, commit.Hash)
+		if !prediction.Clean {
+			prediction.Commit = commit.Hash
+			// The scratch commit means nothing to the user; label it as the
+			// rebase in progress
+			for _, c := range prediction.Conflicts {
+				for j, hunk := range c.Hunks {
+					c.Hunks[j] = strings.ReplaceAll(hunk, "<<<<<<< "+scratch, "<<<<<<< rebased")
+				}
+			}
+			return prediction
+		}
+		tree = result
+	}
+	return MergePrediction{Clean: true, Conflicts: []PredictedConflict{}}
+}
+
+// scratchCommit writes an unreferenced commit of tree on parent. A fixed
+// identity keeps it working when user.name/email aren't configured.
+func scratchCommit(repoPath, tree, parent string) (string, error) {
+	cmd := exec.Command("git", "commit-tree", tree, "-p", parent, "-m", "gitty conflict prediction")
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=gitty", "GIT_AUTHOR_EMAIL=gitty@localhost",
+		"GIT_COMMITTER_NAME=gitty", "GIT_COMMITTER_EMAIL=gitty@localhost")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("commit-tree failed: %s", strings.TrimSpace(string(output)))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// mergeTree merges two commits without touching the working tree and
+// returns the prediction and the resulting tree
+func mergeTree(repoPath, ours, theirs string) (MergePrediction, string) {
+	prediction := MergePrediction{Conflicts: []PredictedConflict{}}
+
+	cmd := exec.Command("git", "merge-tree", "--write-tree", "-z", ours, theirs)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		prediction.Clean = true
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		// Exit status 1 means conflicts; the output describes them
+	case errors.As(err, &exitErr):
+		prediction.Error = "merge-tree failed: " + strings.TrimSpace(string(exitErr.Stderr))
+		return prediction, ""
+	default:
+		prediction.Error = "merge-tree failed: " + err.Error()
+		return prediction, ""
+	}
+
+	tree, conflicts := parseMergeTree(string(output))
+	for i := range conflicts {
+		conflicts[i].Hunks = conflictHunks(blobContent(repoPath, tree+":"+conflicts[i].Path))
+	}
+	if !prediction.Clean {
+		prediction.Conflicts = conflicts
+	}
+	return prediction, tree
+}
+
+// parseMergeTree reads `merge-tree --write-tree -z` output: the tree, the
+// conflicted "<mode> <oid> <stage>\t<path>" entries, an empty field, then
+// messages as "<n>\0<path>...\0<type>\0<message>\0"
+func parseMergeTree(output string) (string, []PredictedConflict) {
+	fields := strings.Split(output, "\x00")
+	if len(fields) == 0 {
+		return "", nil
+	}
+	tree := fields[0]
+
+	var conflicts []PredictedConflict
+	index := map[string]int{}
+	add := func(path string) int {
+		if i, ok := index[path]; ok {
+			return i
+		}
+		index[path] = len(conflicts)
+		conflicts = append(conflicts, PredictedConflict{Path: path})
+		return len(conflicts) - 1
+	}
+
+	i := 1
+	for ; i < len(fields) && fields[i] != ""; i++ {
+		if _, path, ok := strings.Cut(fields[i], "\t"); ok {
+			add(path)
+		}
+	}
+	for i++; i < len(fields); {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil || i+n+2 >= len(fields) {
+			break
+		}
+		paths, kind, message := fields[i+1:i+1+n], fields[i+1+n], fields[i+2+n]
+		i += n + 3
+		if !strings.HasPrefix(kind, "CONFLICT") || len(paths) == 0 {
+			continue
+		}
+		c := &conflicts[add(paths[0])]
+		if c.Type == "" {
+			c.Type = strings.Trim(strings.TrimPrefix(kind, "CONFLICT"), " ()")
+			c.Message = strings.TrimSpace(message)
+		}
+	}
+	return tree, conflicts
+}
+
+func blobContent(repoPath, spec string) string {
+	cmd := exec.Command("git", "cat-file", "blob", spec)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return string(output)
+}
+
+// conflictHunks cuts the conflict-marked regions, with a little context,
+// out of a merged file
+func conflictHunks(content string) []string {
+	lines := strings.Split(content, "\n")
+	var hunks []string
+	for i := 0; i < len(lines) && len(hunks) < maxConflictHunks; i++ {
+		if !strings.HasPrefix(lines[i], "<<<<<<< ") {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !strings.HasPrefix(lines[end], ">>>>>>> ") {
+			end++
+		}
+		start := max(0, i-conflictHunkContext)
+		stop := min(len(lines), end+1+conflictHunkContext)
+		hunks = append(hunks, strings.Join(lines[start:stop], "\n"))
+		i = end
+	}
+	return hunks
+}
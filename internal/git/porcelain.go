@@ -0,0 +1,199 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Change kinds, from the porcelain v2 record type
+const (
+	ChangeOrdinary  = "ordinary"
+	ChangeRenamed   = "renamed" // rename or copy
+	ChangeUnmerged  = "unmerged"
+	ChangeUntracked = "untracked"
+	ChangeIgnored   = "ignored"
+)
+
+// BranchInfo holds the `# branch.*` headers of porcelain v2 output
+type BranchInfo struct {
+	Oid      string // "(initial)" before the first commit
+	Head     string // "(detached)" when HEAD is detached
+	Upstream string
+	Ahead    int
+	Behind   int
+}
+
+// IndexState is the staged (X) half of the status code
+func (c Change) IndexState() byte {
+	if len(c.Status) < 1 {
+		return ' '
+	}
+	return c.Status[0]
+}
+
+// WorktreeState is the unstaged (Y) half of the status code
+func (c Change) WorktreeState() byte {
+	if len(c.Status) < 2 {
+		return ' '
+	}
+	return c.Status[1]
+}
+
+// IsStaged reports whether the index differs from HEAD for this path.
+// Unmerged paths are not considered staged.
+func (c Change) IsStaged() bool {
+	if c.Kind == ChangeUnmerged {
+		return false
+	}
+	x := c.IndexState()
+	return x != ' ' && x != '?' && x != '!'
+}
+
+// HasWorktreeChanges reports whether the working tree differs from the index
+func (c Change) HasWorktreeChanges() bool {
+	return c.WorktreeState() != ' '
+}
+
+// IsConflict reports whether the path has unresolved merge conflicts
+func (c Change) IsConflict() bool {
+	return c.Kind == ChangeUnmerged
+}
+
+// IsSubmodule reports whether the path is a submodule
+func (c Change) IsSubmodule() bool {
+	return c.Submodule != ""
+}
+
+// Paths returns every path git needs to operate on this change, so a
+// staged rename can be unstaged as a whole
+func (c Change) Paths() []string {
+	if c.OrigPath != "" {
+		return []string{c.OrigPath, c.File}
+	}
+	return []string{c.File}
+}
+
+// GetStatusEntries runs one `git status --porcelain=v2 --branch -z` and
+// returns the branch headers and every change entry
+func GetStatusEntries(repoPath string) (BranchInfo, []Change, error) {
+	cmd := exec.Command("git", "--no-optional-locks", "status", "--porcelain=v2", "--branch", "-z")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return BranchInfo{}, nil, fmt.Errorf("git status failed: %w", err)
+	}
+	branch, changes := ParsePorcelainV2(string(output))
+	return branch, changes, nil
+}
+
+// ParsePorcelainV2 parses `git status --porcelain=v2 -z` output. Paths are
+// taken verbatim, so names with spaces, quotes or newlines survive intact.
+// Status codes use porcelain v1 notation ("M ", " M", "??") so they can be
+// compared against the familiar two-letter forms.
+func ParsePorcelainV2(output string) (BranchInfo, []Change) {
+	var branch BranchInfo
+	var changes []Change
+
+	records := strings.Split(output, "\x00")
+	for i := 0; i < len(records); i++ {
+		rec := records[i]
+		if rec == "" {
+			continue
+		}
+
+		switch rec[0] {
+		case '#':
+			parseBranchHeader(rec, &branch)
+
+		case '1':
+			// 1 XY sub mH mI mW hH hI path
+			fields := strings.SplitN(rec, " ", 9)
+			if len(fields) < 9 {
+				continue
+			}
+			changes = append(changes, Change{
+				File:      fields[8],
+				Status:    v1Status(fields[1]),
+				Kind:      ChangeOrdinary,
+				Submodule: submoduleState(fields[2]),
+			})
+
+		case '2':
+			// 2 XY sub mH mI mW hH hI Xscore path, then origPath as the next record
+			fields := strings.SplitN(rec, " ", 10)
+			if len(fields) < 10 {
+				continue
+			}
+			change := Change{
+				File:      fields[9],
+				Status:    v1Status(fields[1]),
+				Kind:      ChangeRenamed,
+				Submodule: submoduleState(fields[2]),
+			}
+			if len(fields[8]) > 1 {
+				change.Score, _ = strconv.Atoi(fields[8][1:])
+			}
+			if i+1 < len(records) {
+				i++
+				change.OrigPath = records[i]
+			}
+			changes = append(changes, change)
+
+		case 'u':
+			// u XY sub m1 m2 m3 mW h1 h2 h3 path
+			fields := strings.SplitN(rec, " ", 11)
+			if len(fields) < 11 {
+				continue
+			}
+			changes = append(changes, Change{
+				File:      fields[10],
+				Status:    fields[1],
+				Kind:      ChangeUnmerged,
+				Submodule: submoduleState(fields[2]),
+			})
+
+		case '?':
+			changes = append(changes, Change{File: rec[2:], Status: "??", Kind: ChangeUntracked})
+
+		case '!':
+			changes = append(changes, Change{File: rec[2:], Status: "!!", Kind: ChangeIgnored})
+		}
+	}
+
+	return branch, changes
+}
+
+func parseBranchHeader(rec string, branch *BranchInfo) {
+	fields := strings.Fields(rec)
+	if len(fields) < 3 {
+		return
+	}
+	switch fields[1] {
+	case "branch.oid":
+		branch.Oid = fields[2]
+	case "branch.head":
+		branch.Head = fields[2]
+	case "branch.upstream":
+		branch.Upstream = fields[2]
+	case "branch.ab":
+		if len(fields) >= 4 {
+			fmt.Sscanf(fields[2], "+%d", &branch.Ahead)
+			fmt.Sscanf(fields[3], "-%d", &branch.Behind)
+		}
+	}
+}
+
+// v1Status converts v2's "." placeholders to the spaces v1 uses
+func v1Status(xy string) string {
+	return strings.ReplaceAll(xy, ".", " ")
+}
+
+// submoduleState returns the "S<c><m><u>" field for submodules and "" otherwise
+func submoduleState(sub string) string {
+	if strings.HasPrefix(sub, "S") {
+		return sub
+	}
+	return ""
+}
@@ -0,0 +1,165 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// PromptStatus is the compact status shown in a shell prompt
+type PromptStatus struct {
+	Branch    string    `json:"branch"`
+	Detached  bool      `json:"detached"`
+	Upstream  string    `json:"upstream,omitempty"`
+	Ahead     int       `json:"ahead"`
+	Behind    int       `json:"behind"`
+	Staged    int       `json:"staged"`
+	Unstaged  int       `json:"unstaged"`
+	Untracked int       `json:"untracked"`
+	Conflicts int       `json:"conflicts"`
+	Operation Operation `json:"operation,omitempty"`
+	Progress  string    `json:"progress,omitempty"` // rebase step, e.g. "2/5"
+}
+
+// promptCache is stored under .git/gitty so it goes away with the repo
+type promptCache struct {
+	Key     string       `json:"key"`
+	Written time.Time    `json:"written"`
+	Status  PromptStatus `json:"status"`
+}
+
+// FindRepoRoot returns the top of the working tree containing dir and its
+// git directory, which is not .git in linked worktrees and submodules
+func FindRepoRoot(dir string) (root, gitDir string, ok bool) {
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel", "--git-dir")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", "", false
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 {
+		return "", "", false
+	}
+	root, gitDir = lines[0], lines[1]
+	if !filepath.IsAbs(gitDir) {
+		// git prints it relative to the directory it ran in
+		gitDir = filepath.Join(dir, gitDir)
+	}
+	return root, filepath.Clean(gitDir), true
+}
+
+// GetPromptStatus returns prompt status from a single
+// `git status --porcelain=v2 --branch` call (see GetStatusEntries). Results are cached and reused
+// while the index, HEAD and operation state are unchanged and the cache is
+// younger than maxAge, so repeated prompt redraws only pay for the rev-parse
+// in FindRepoRoot.
+func GetPromptStatus(repoPath, gitDir string, maxAge time.Duration) (PromptStatus, error) {
+	cachePath := filepath.Join(gitDir, "gitty", "prompt-cache.json")
+	key := promptCacheKey(gitDir)
+
+	if maxAge > 0 {
+		if data, err := os.ReadFile(cachePath); err == nil {
+			var cached promptCache
+			if json.Unmarshal(data, &cached) == nil && cached.Key == key && time.Since(cached.Written) < maxAge {
+				return cached.Status, nil
+			}
+		}
+	}
+
+	branch, changes, err := GetStatusEntries(repoPath)
+	if err != nil {
+		return PromptStatus{}, err
+	}
+
+	status := newPromptStatus(branch, changes)
+	status.Operation = operationIn(gitDir)
+	if status.Operation == OpRebase {
+		status.Progress = rebaseProgress(gitDir)
+	}
+
+	if maxAge > 0 {
+		if data, err := json.Marshal(promptCache{Key: key, Written: time.Now(), Status: status}); err == nil {
+			os.MkdirAll(filepath.Dir(cachePath), 0755)
+			os.WriteFile(cachePath, data, 0644)
+		}
+	}
+
+	return status, nil
+}
+
+// promptCacheKey changes whenever staging, HEAD or the operation state does
+func promptCacheKey(gitDir string) string {
+	var sb strings.Builder
+	if info, err := os.Stat(filepath.Join(gitDir, "index")); err == nil {
+		fmt.Fprintf(&sb, "%d:%d;", info.ModTime().UnixNano(), info.Size())
+	}
+	if head, err := os.ReadFile(filepath.Join(gitDir, "HEAD")); err == nil {
+		sb.Write(head)
+	}
+	for _, name := range []string{"rebase-merge", "rebase-apply", "MERGE_HEAD", "CHERRY_PICK_HEAD", "REVERT_HEAD", "BISECT_LOG"} {
+		if _, err := os.Stat(filepath.Join(gitDir, name)); err == nil {
+			sb.WriteString(";" + name)
+		}
+	}
+	return sb.String()
+}
+
+// newPromptStatus condenses parsed status entries into prompt counts
+func newPromptStatus(branch BranchInfo, changes []Change) PromptStatus {
+	status := PromptStatus{
+		Branch:   branch.Head,
+		Upstream: branch.Upstream,
+		Ahead:    branch.Ahead,
+		Behind:   branch.Behind,
+	}
+
+	// Show the short commit in place of "(detached)"
+	if branch.Head == "(detached)" {
+		status.Detached = true
+		if len(branch.Oid) >= 7 {
+			status.Branch = branch.Oid[:7]
+		}
+	}
+
+	for _, c := range changes {
+		switch {
+		case c.IsConflict():
+			status.Conflicts++
+		case c.Kind == ChangeUntracked:
+			status.Untracked++
+		default:
+			if c.IsStaged() {
+				status.Staged++
+			}
+			if c.HasWorktreeChanges() {
+				status.Unstaged++
+			}
+		}
+	}
+
+	return status
+}
+
+// rebaseProgress reads the current step from the rebase state directory
+func rebaseProgress(gitDir string) string {
+	read := func(path string) string {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+
+	if step := read(filepath.Join(gitDir, "rebase-merge", "msgnum")); step != "" {
+		return step + "/" + read(filepath.Join(gitDir, "rebase-merge", "end"))
+	}
+	if step := read(filepath.Join(gitDir, "rebase-apply", "next")); step != "" {
+		return step + "/" + read(filepath.Join(gitDir, "rebase-apply", "last"))
+	}
+	return ""
+}
@@ -0,0 +1,461 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RebaseOptions are the flags of an interactive rebase
+type RebaseOptions struct {
+	Onto       string // replay the commits onto this ref instead of in place
+	Autosquash bool   // fixup!/squash! commits were moved next to their targets
+	UpdateRefs bool   // move other branches that point into the rebased commits
+}
+
+// rebaseCount is the number of commits the plan covers; exec lines are
+// not commits
+func rebaseCount(commits []RebaseCommit) int {
+	n := 0
+	for _, c := range commits {
+		if c.Action != "exec" {
+			n++
+		}
+	}
+	return n
+}
+
+// rebaseBase is the commit the plan is replayed on
+func rebaseBase(commits []RebaseCommit, opts RebaseOptions) string {
+	if opts.Onto != "" {
+		return opts.Onto
+	}
+	return fmt.Sprintf("HEAD~%d", rebaseCount(commits))
+}
+
+// ValidateRebase checks a plan (newest first) for mistakes git would only
+// report after the rebase has started
+func ValidateRebase(commits []RebaseCommit) error {
+	if rebaseCount(commits) == 0 {
+		return fmt.Errorf("no commits to rebase")
+	}
+	for _, c := range commits {
+		if c.Action == "exec" && strings.ContainsAny(c.Command, "\r\n") {
+			return fmt.Errorf("exec command must be a single line")
+		}
+	}
+	for i := len(commits) - 1; i >= 0; i-- {
+		switch c := commits[i]; c.Action {
+		case "drop", "exec":
+			continue
+		case "squash", "fixup":
+			return fmt.Errorf("cannot %s %s: no earlier commit to combine with", c.Action, c.Hash)
+		}
+		return nil
+	}
+	return nil
+}
+
+// AutosquashPlan moves "fixup! X" and "squash! X" commits (newest first
+// plan) after the commit they name, as git rebase --autosquash does, and
+// sets their action. X is the target's subject or a hash prefix.
+func AutosquashPlan(commits []RebaseCommit) []RebaseCommit {
+	plan, _ := autosquash(commits)
+	return plan
+}
+
+// autosquash builds the AutosquashPlan and reports the hashes of the
+// commits it moved under a target
+func autosquash(commits []RebaseCommit) ([]RebaseCommit, map[string]bool) {
+	// Work oldest first, like the todo
+	var order []RebaseCommit
+	for i := len(commits) - 1; i >= 0; i-- {
+		order = append(order, commits[i])
+	}
+
+	var plan []RebaseCommit
+	placed := map[int]bool{}
+	moved := map[string]bool{}
+	for i, c := range order {
+		if placed[i] {
+			continue
+		}
+		plan = append(plan, c)
+		if c.Action == "exec" {
+			continue
+		}
+		// Fixups of this commit, and fixups of those, in their order
+		for j := i + 1; j < len(order); j++ {
+			action, target, ok := autosquashTarget(order[j].Message)
+			if placed[j] || !ok || order[j].Action == "exec" {
+				continue
+			}
+			if target == c.Message || (len(target) >= 4 && strings.HasPrefix(c.Hash, target)) {
+				fixup := order[j]
+				fixup.Action = action
+				plan = append(plan, fixup)
+				placed[j] = true
+				moved[fixup.Hash] = true
+			}
+		}
+	}
+
+	out := make([]RebaseCommit, 0, len(plan))
+	for i := len(plan) - 1; i >= 0; i-- {
+		out = append(out, plan[i])
+	}
+	return out, moved
+}
+
+// UndoAutosquash takes the commits AutosquashPlan moved out of plan and puts
+// them back after the commit that preceded them in original, the plan
+// autosquash was applied to. Their action is restored unless it was changed
+// since; every other edit to plan is kept.
+func UndoAutosquash(plan, original []RebaseCommit) []RebaseCommit {
+	auto, moved := autosquash(original)
+	autoAction := map[string]string{}
+	for _, c := range auto {
+		autoAction[c.Hash] = c.Action
+	}
+
+	current := map[string]RebaseCommit{}
+	var out []RebaseCommit
+	for _, c := range plan {
+		if c.Action != "exec" && moved[c.Hash] {
+			current[c.Hash] = c
+			continue
+		}
+		out = append(out, c)
+	}
+
+	// Oldest first, so the commit each one goes after is already in place
+	for i := len(original) - 1; i >= 0; i-- {
+		orig := original[i]
+		c, ok := current[orig.Hash]
+		if orig.Action == "exec" || !ok {
+			continue
+		}
+		if c.Action == autoAction[c.Hash] {
+			c.Action = orig.Action
+		}
+
+		at := len(out)
+		for j := i + 1; j < len(original); j++ {
+			if k := planIndex(out, original[j].Hash); k >= 0 {
+				at = k
+				break
+			}
+		}
+		// Exec lines added after that commit still run before this one
+		for at > 0 && out[at-1].Action == "exec" {
+			at--
+		}
+		out = append(out[:at], append([]RebaseCommit{c}, out[at:]...)...)
+	}
+	return out
+}
+
+// planIndex finds a commit in a plan by hash, -1 if it isn't there
+func planIndex(plan []RebaseCommit, hash string) int {
+	for i, c := range plan {
+		if c.Action != "exec" && c.Hash == hash {
+			return i
+		}
+	}
+	return -1
+}
+
+// autosquashTarget reads "fixup! fixup! X" as a fixup of X
+func autosquashTarget(subject string) (string, string, bool) {
+	action := ""
+	for {
+		switch {
+		case strings.HasPrefix(subject, "fixup! "):
+			subject = strings.TrimPrefix(subject, "fixup! ")
+			if action == "" {
+				action = "fixup"
+			}
+		case strings.HasPrefix(subject, "squash! "):
+			subject = strings.TrimPrefix(subject, "squash! ")
+			if action == "" {
+				action = "squash"
+			}
+		default:
+			return action, subject, action != ""
+		}
+	}
+}
+
+// ExecuteRebase runs git rebase -i with the plan (newest first) as the
+// todo. Rewords are applied by amending the message right after the pick,
+// so no editor opens; squash messages are taken as git proposes them.
+func ExecuteRebase(repoPath string, commits []RebaseCommit, opts RebaseOptions) error {
+	if err := ValidateRebase(commits); err != nil {
+		return err
+	}
+
+	// Messages outlive this call: a rebase that stops (edit, conflict)
+	// still runs the remaining amends on continue
+	msgDir, err := gitPath(repoPath, "gitty/rebase")
+	if err != nil {
+		return err
+	}
+	os.RemoveAll(msgDir)
+	if err := os.MkdirAll(msgDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", msgDir, err)
+	}
+
+	todo, err := buildRebaseTodo(repoPath, commits, opts, msgDir)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp("", "gitty-rebase-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmpFile.WriteString(todo); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write rebase todo: %w", err)
+	}
+	tmpFile.Close()
+
+	args := []string{"rebase", "-i"}
+	if opts.UpdateRefs {
+		args = append(args, "--update-refs")
+	}
+	if opts.Onto != "" {
+		args = append(args, "--onto", opts.Onto)
+	}
+	args = append(args, fmt.Sprintf("HEAD~%d", rebaseCount(commits)))
+
+	// git runs the sequence editor through the shell with the todo path as
+	// "$1"; our path is passed in the environment, never in the script
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(),
+		"GITTY_REBASE_TODO="+tmpPath,
+		`GIT_SEQUENCE_EDITOR=cp "$GITTY_REBASE_TODO"`,
+		"GIT_EDITOR=true")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rebase failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// buildRebaseTodo writes the todo, oldest first
+func buildRebaseTodo(repoPath string, commits []RebaseCommit, opts RebaseOptions, msgDir string) (string, error) {
+	var branchRefs map[string][]string
+	if opts.UpdateRefs {
+		branchRefs = branchesByCommit(repoPath)
+	}
+
+	var lines []string
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		action := c.Action
+		if action == "" {
+			action = "pick"
+		}
+		if action == "exec" {
+			lines = append(lines, "exec "+c.Command)
+			continue
+		}
+
+		if action == "reword" && c.NewMessage != "" {
+			msgPath := filepath.Join(msgDir, c.Hash)
+			if err := os.WriteFile(msgPath, []byte(c.NewMessage+"\n"), 0o644); err != nil {
+				return "", fmt.Errorf("failed to write message for %s: %w", c.Hash, err)
+			}
+			lines = append(lines,
+				fmt.Sprintf("pick %s %s", c.Hash, c.Message),
+				"exec git commit --amend --only --allow-empty --no-verify -F "+shellQuote(msgPath))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s %s %s", action, c.Hash, c.Message))
+		}
+
+		for full, refs := range branchRefs {
+			if strings.HasPrefix(full, c.Hash) {
+				for _, ref := range refs {
+					lines = append(lines, "update-ref "+ref)
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// branchesByCommit maps commit hashes to the local branches on them,
+// leaving out the checked-out branch, which the rebase moves itself
+func branchesByCommit(repoPath string) map[string][]string {
+	cmd := exec.Command("git", "symbolic-ref", "-q", "HEAD")
+	cmd.Dir = repoPath
+	head, _ := cmd.Output()
+	current := strings.TrimSpace(string(head))
+
+	cmd = exec.Command("git", "for-each-ref", "--format=%(objectname) %(refname)", "refs/heads/")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	refs := map[string][]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		hash, ref, ok := strings.Cut(line, " ")
+		if ok && ref != current {
+			refs[hash] = append(refs[hash], ref)
+		}
+	}
+	return refs
+}
+
+// gitPath resolves a path inside the git directory, which may not be .git
+// (worktrees, submodules)
+func gitPath(repoPath, name string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-path", name)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %s", repoPath)
+	}
+	path := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(repoPath, path)
+	}
+	return path, nil
+}
+
+// gitDir resolves the repository's own git directory. In a linked worktree
+// that is .git/worktrees/<name>, which holds its HEAD, index and operation
+// state.
+func gitDir(repoPath string) (string, error) {
+	path, err := gitPath(repoPath, ".")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(path), nil
+}
+
+// shellQuote quotes s as one word for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RebaseState is a rebase that stopped and waits on the user
+type RebaseState struct {
+	Step        int      // todo item being applied, 1-based
+	Total       int      // todo items in the whole rebase
+	HeadName    string   // branch being rebased, empty when detached
+	Onto        string   // short hash of the new base
+	StoppedAt   string   // short hash of the commit it stopped on, if any
+	Done        []string // todo lines applied so far, the last one is current
+	Todo        []string // todo lines still to run
+	Conflicts   []string // unmerged paths
+	Interactive bool     // rebase -i (rebase-merge); am-based rebases have no todo
+}
+
+// GetRebaseState reads rebase-merge (or rebase-apply) in the git
+// directory; nil when no rebase is in progress
+func GetRebaseState(repoPath string) *RebaseState {
+	dir, err := gitDir(repoPath)
+	if err != nil {
+		return nil
+	}
+	read := func(path string) string {
+		data, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+
+	var state RebaseState
+	switch {
+	case exists("rebase-merge"):
+		state.Interactive = true
+		state.Step, _ = strconv.Atoi(read("rebase-merge/msgnum"))
+		state.Total, _ = strconv.Atoi(read("rebase-merge/end"))
+		state.HeadName = read("rebase-merge/head-name")
+		state.Onto = ShortHash(read("rebase-merge/onto"))
+		state.StoppedAt = ShortHash(read("rebase-merge/stopped-sha"))
+		state.Done = todoLines(read("rebase-merge/done"))
+		state.Todo = todoLines(read("rebase-merge/git-rebase-todo"))
+	case exists("rebase-apply"):
+		state.Step, _ = strconv.Atoi(read("rebase-apply/next"))
+		state.Total, _ = strconv.Atoi(read("rebase-apply/last"))
+		state.HeadName = read("rebase-apply/head-name")
+		state.Onto = ShortHash(read("rebase-apply/onto"))
+	default:
+		return nil
+	}
+	if state.HeadName == "detached HEAD" {
+		state.HeadName = ""
+	}
+
+	state.Conflicts = GetConflictFiles(repoPath)
+	return &state
+}
+
+// todoLines drops the comments and blank lines of a todo file and
+// shortens the full hashes git writes into it
+func todoLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if fields := strings.SplitN(line, " ", 3); len(fields) >= 2 && len(fields[1]) == 40 && fields[0] != "exec" && fields[0] != "x" {
+			fields[1] = fields[1][:7]
+			line = strings.Join(fields, " ")
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// EditRebaseTodoCommand opens the remaining todo in the user's editor; run
+// it with the terminal attached
+func EditRebaseTodoCommand(repoPath string) *exec.Cmd {
+	cmd := exec.Command("git", "rebase", "--edit-todo")
+	cmd.Dir = repoPath
+	return cmd
+}
+
+func AbortRebase(repoPath string) error {
+	return AbortOperation(repoPath, OpRebase)
+}
+
+// ContinueRebase commits the resolved step with its original message and
+// runs the rest of the todo
+func ContinueRebase(repoPath string) error {
+	return ContinueOperation(repoPath, OpRebase)
+}
+
+func SkipRebase(repoPath string) error {
+	return SkipOperation(repoPath, OpRebase)
+}
+
+func IsRebaseInProgress(repoPath string) bool {
+	dir, err := gitDir(repoPath)
+	if err != nil {
+		return false
+	}
+	rebaseMerge := filepath.Join(dir, "rebase-merge")
+	rebaseApply := filepath.Join(dir, "rebase-apply")
+	_, err1 := os.Stat(rebaseMerge)
+	_, err2 := os.Stat(rebaseApply)
+	return err1 == nil || err2 == nil
+}
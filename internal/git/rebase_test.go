@@ -0,0 +1,50 @@
+package git
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestUndoAutosquashKeepsEdits turns autosquash on, edits the plan, and
+// checks that turning it off only moves the fixup back
+func TestUndoAutosquashKeepsEdits(t *testing.T) {
+	original := []RebaseCommit{ // newest first
+		{Hash: "dddddddd", Message: "Add docs", Action: "pick"},
+		{Hash: "cccccccc", Message: "fixup! Add parser", Action: "pick"},
+		{Hash: "bbbbbbbb", Message: "Add lexer", Action: "pick"},
+		{Hash: "aaaaaaaa", Message: "Add parser", Action: "pick"},
+	}
+
+	plan := AutosquashPlan(original)
+	want := []string{"dddddddd pick", "bbbbbbbb pick", "cccccccc fixup", "aaaaaaaa pick"}
+	if got := planSummary(plan); !reflect.DeepEqual(got, want) {
+		t.Fatalf("autosquash plan = %v, want %v", got, want)
+	}
+
+	// Edits made while autosquash is on: reword, drop, and an exec line
+	plan[0].Action = "drop"
+	plan[1].NewMessage = "Add a lexer"
+	plan = append(plan[:2], append([]RebaseCommit{{Action: "exec", Command: "make test"}}, plan[2:]...)...)
+
+	undone := UndoAutosquash(plan, original)
+	got := planSummary(undone)
+	want = []string{"dddddddd drop", "cccccccc pick", "bbbbbbbb pick", "exec make test", "aaaaaaaa pick"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("after undo = %v, want %v", got, want)
+	}
+	if undone[2].NewMessage != "Add a lexer" {
+		t.Errorf("reword was lost")
+	}
+}
+
+func planSummary(plan []RebaseCommit) []string {
+	var lines []string
+	for _, c := range plan {
+		if c.Action == "exec" {
+			lines = append(lines, "exec "+c.Command)
+		} else {
+			lines = append(lines, c.Hash+" "+c.Action)
+		}
+	}
+	return lines
+}
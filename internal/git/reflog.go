@@ -0,0 +1,58 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ReflogEntry is one move of HEAD or a branch
+type ReflogEntry struct {
+	Hash     string   // where the ref pointed after the move
+	Parents  []string // parents of that commit
+	Selector string   // HEAD@{2}, main@{0}
+	Action   string   // "commit", "commit (amend)", "reset", "rebase (finish)", "checkout", ...
+	Kind     string   // first word of Action: commit, reset, rebase, checkout, merge, pull, ...
+	Message  string   // rest of the reflog subject: "moving from main to feature"
+	Subject  string   // subject of the commit
+	Date     string
+}
+
+// GetReflog lists the latest count moves of ref (HEAD or a branch), newest
+// first. Commits no branch reaches any more are still listed here until
+// git gc expires them.
+func GetReflog(repoPath, ref string, count int) []ReflogEntry {
+	cmd := exec.Command("git", "reflog", "show", fmt.Sprintf("-%d", count),
+		"--format=%h%x1f%p%x1f%gd%x1f%gs%x1f%s%x1f%ar", ref, "--")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var entries []ReflogEntry
+	for _, line := range strings.Split(string(output), "\n") {
+		parts := strings.Split(line, "\x1f")
+		if len(parts) < 6 {
+			continue
+		}
+		entry := ReflogEntry{
+			Hash:     parts[0],
+			Parents:  strings.Fields(parts[1]),
+			Selector: parts[2],
+			Subject:  parts[4],
+			Date:     parts[5],
+		}
+		// "checkout: moving from main to feature", "commit (amend): Fix typo"
+		action, message, ok := strings.Cut(parts[3], ": ")
+		if !ok {
+			action, message = parts[3], ""
+		}
+		entry.Action, entry.Message = action, message
+		if fields := strings.Fields(action); len(fields) > 0 {
+			entry.Kind = fields[0]
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,126 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// ResetMode is how `git reset` treats the index and working tree
+type ResetMode string
+
+const (
+	ResetSoft  ResetMode = "soft"  // keep index and working tree: undone commits become staged
+	ResetMixed ResetMode = "mixed" // reset the index: everything becomes unstaged
+	ResetHard  ResetMode = "hard"  // reset index and working tree: everything is discarded
+	ResetKeep  ResetMode = "keep"  // like hard, but refuses to touch files with local changes
+)
+
+// ResetModes in the order the undo view cycles through them
+var ResetModes = []ResetMode{ResetSoft, ResetMixed, ResetHard, ResetKeep}
+
+// ResetPreview is what resetting HEAD to Target would change. The file lists
+// don't depend on the mode; Effect sorts them for one.
+type ResetPreview struct {
+	Target  string
+	Commits []Commit // commits that leave the branch, newest first
+	Dirty   []string // files with uncommitted changes, staged or not
+
+	committed     []string // differ between Target and HEAD
+	indexVsTarget []string // index differs from Target
+	unstaged      []string // working tree differs from the index
+	worktree      []string // working tree differs from Target
+}
+
+// ResetEffect lists the files whose changes end up in each state after a
+// reset in one mode
+type ResetEffect struct {
+	Staged    []string
+	Unstaged  []string
+	Discarded []string
+	Blocked   []string // keep mode: local changes in files the reset must update; git refuses
+}
+
+// PreviewReset gathers what a reset of HEAD to target would do
+func PreviewReset(repoPath, target string) (ResetPreview, error) {
+	preview := ResetPreview{Target: target}
+
+	cmd := exec.Command("git", "log", logFormat, target+"..HEAD")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return preview, fmt.Errorf("cannot resolve %s", target)
+	}
+	preview.Commits = parseCommitLog(string(output))
+
+	diffs := []struct {
+		dst  *[]string
+		args []string
+	}{
+		{&preview.committed, []string{target, "HEAD"}},
+		{&preview.Dirty, []string{"HEAD"}},
+		{&preview.indexVsTarget, []string{"--cached", target}},
+		{&preview.unstaged, nil},
+		{&preview.worktree, []string{target}},
+	}
+	for _, diff := range diffs {
+		files, err := diffNames(repoPath, diff.args...)
+		if err != nil {
+			return preview, err
+		}
+		*diff.dst = files
+	}
+	return preview, nil
+}
+
+// Effect sorts the changed files by what happens to them in mode
+func (p ResetPreview) Effect(mode ResetMode) ResetEffect {
+	switch mode {
+	case ResetSoft:
+		return ResetEffect{Staged: p.indexVsTarget, Unstaged: p.unstaged}
+	case ResetMixed:
+		return ResetEffect{Unstaged: p.worktree}
+	case ResetHard:
+		return ResetEffect{Discarded: p.worktree}
+	case ResetKeep:
+		// The index is reset to the target, so staged changes end up unstaged
+		var effect ResetEffect
+		for _, f := range p.worktree {
+			if !slices.Contains(p.committed, f) {
+				effect.Unstaged = append(effect.Unstaged, f)
+			}
+		}
+		for _, f := range p.committed {
+			if slices.Contains(p.Dirty, f) {
+				effect.Blocked = append(effect.Blocked, f)
+			} else {
+				effect.Discarded = append(effect.Discarded, f)
+			}
+		}
+		return effect
+	}
+	return ResetEffect{}
+}
+
+// Reset moves the current branch to target in mode
+func Reset(repoPath, target string, mode ResetMode) error {
+	output, err := Execute(repoPath, "reset", "--"+string(mode), target)
+	if err != nil {
+		// --keep explains itself on the first line; skip hints
+		return fmt.Errorf("%s", firstLine(output))
+	}
+	return nil
+}
+
+// diffNames lists the paths `git diff --name-only args` reports, without
+// rename detection so both sides of a rename show up
+func diffNames(repoPath string, args ...string) ([]string, error) {
+	cmd := exec.Command("git", append([]string{"diff", "-z", "--name-only", "--no-renames"}, args...)...)
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff %s failed", strings.Join(args, " "))
+	}
+	return splitNul(string(output)), nil
+}
@@ -0,0 +1,73 @@
+package git
+
+import "sync"
+
+// SnapshotSchemaVersion is bumped whenever a field in the JSON export is
+// renamed, removed or changes meaning. Adding fields does not bump it.
+// See SCHEMA.md for the documented format.
+const SnapshotSchemaVersion = 1
+
+// Snapshot is the full repository state exported as JSON
+type Snapshot struct {
+	SchemaVersion int      `json:"schema_version"`
+	RepoPath      string   `json:"repo_path"`
+	Head          string   `json:"head"`
+	Status        Status   `json:"status"`
+	Changes       []Change `json:"changes"`
+	Branches      []Branch `json:"branches"`
+	RecentCommits []Commit `json:"recent_commits"`
+	Stashes       []Stash  `json:"stashes"`
+	Tags          []Tag    `json:"tags"`
+}
+
+// GetSnapshot gathers status, changes, branches, recent commits, stashes and
+// tags in one call. The underlying git commands run concurrently.
+func GetSnapshot(repoPath string, commitCount int) Snapshot {
+	snap := Snapshot{
+		SchemaVersion: SnapshotSchemaVersion,
+		RepoPath:      repoPath,
+	}
+
+	var wg sync.WaitGroup
+	run := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	run(func() { snap.Head = GetCurrentCommitHash(repoPath) })
+	run(func() {
+		// Status and changes come from the same status call so they agree
+		if branch, changes, err := GetStatusEntries(repoPath); err == nil {
+			snap.Status, snap.Changes = newStatus(branch, changes), changes
+		} else {
+			snap.Status = Status{Branch: "unknown"}
+		}
+	})
+	run(func() { snap.Branches = append(GetBranches(repoPath), GetRemoteBranches(repoPath)...) })
+	run(func() { snap.RecentCommits = GetCommitLog(repoPath, commitCount) })
+	run(func() { snap.Stashes = GetStashList(repoPath) })
+	run(func() { snap.Tags = GetTags(repoPath) })
+	wg.Wait()
+
+	// Empty lists are [] rather than null so consumers can iterate unconditionally
+	if snap.Changes == nil {
+		snap.Changes = []Change{}
+	}
+	if snap.Branches == nil {
+		snap.Branches = []Branch{}
+	}
+	if snap.RecentCommits == nil {
+		snap.RecentCommits = []Commit{}
+	}
+	if snap.Stashes == nil {
+		snap.Stashes = []Stash{}
+	}
+	if snap.Tags == nil {
+		snap.Tags = []Tag{}
+	}
+
+	return snap
+}
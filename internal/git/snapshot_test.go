@@ -0,0 +1,77 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+// TestSnapshotGolden pins the JSON snapshot format documented in SCHEMA.md.
+// Run with -update after an intentional schema change.
+func TestSnapshotGolden(t *testing.T) {
+	repo := newTestRepo(t)
+
+	writeFile(t, repo, "README.md", "# fixture\n")
+	writeFile(t, repo, "old.txt", "renamed later\n")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "--quiet", "-m", "Initial commit")
+	runGit(t, repo, "tag", "-a", "v1.0", "-m", "First release")
+
+	writeFile(t, repo, "main.go", "package main\n")
+	runGit(t, repo, "add", "main.go")
+	runGit(t, repo, "commit", "--quiet", "-m", "Add main")
+	runGit(t, repo, "tag", "light")
+	runGit(t, repo, "update-ref", "refs/remotes/origin/main", "HEAD~1")
+	runGit(t, repo, "remote", "add", "origin", "https://example.com/fixture.git")
+	runGit(t, repo, "config", "branch.main.remote", "origin")
+	runGit(t, repo, "config", "branch.main.merge", "refs/heads/main")
+	runGit(t, repo, "branch", "feature", "HEAD~1")
+
+	writeFile(t, repo, "README.md", "# fixture\n\nstashed\n")
+	runGit(t, repo, "stash", "push", "--quiet", "-m", "wip readme")
+
+	runGit(t, repo, "mv", "old.txt", "new name.txt")
+	writeFile(t, repo, "staged.txt", "staged\n")
+	runGit(t, repo, "add", "staged.txt")
+	writeFile(t, repo, "main.go", "package main\n\nfunc main() {}\n")
+	writeFile(t, repo, "untracked ü.txt", "untracked\n")
+
+	snap := GetSnapshot(repo, 10)
+
+	// Paths and relative dates depend on where and when the test runs
+	snap.RepoPath = "REPO"
+	for i := range snap.RecentCommits {
+		snap.RecentCommits[i].Date = "DATE"
+	}
+	for i := range snap.Stashes {
+		snap.Stashes[i].Date = "DATE"
+	}
+	for i := range snap.Tags {
+		snap.Tags[i].Date = "DATE"
+	}
+
+	got, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	golden := filepath.Join("testdata", "snapshot.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("snapshot differs from %s (rerun with -update if intended)\ngot:\n%s", golden, got)
+	}
+}
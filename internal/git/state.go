@@ -0,0 +1,171 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Operation names a multi-step git operation that is waiting on the user
+type Operation string
+
+const (
+	OpNone       Operation = ""
+	OpRebase     Operation = "rebase"
+	OpMerge      Operation = "merge"
+	OpCherryPick Operation = "cherry-pick"
+	OpRevert     Operation = "revert"
+	OpBisect     Operation = "bisect"
+)
+
+// GetOperation reports which operation, if any, is in progress. Rebase wins
+// over the others because a conflicting pick inside a rebase also leaves
+// CHERRY_PICK_HEAD behind.
+func GetOperation(repoPath string) Operation {
+	dir, err := gitDir(repoPath)
+	if err != nil {
+		return OpNone
+	}
+	return operationIn(dir)
+}
+
+// operationIn detects the operation from the state files in gitDir
+func operationIn(gitDir string) Operation {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(gitDir, name))
+		return err == nil
+	}
+
+	switch {
+	case exists("rebase-merge"), exists("rebase-apply"):
+		return OpRebase
+	case exists("MERGE_HEAD"):
+		return OpMerge
+	case exists("CHERRY_PICK_HEAD"):
+		return OpCherryPick
+	case exists("REVERT_HEAD"):
+		return OpRevert
+	case exists("BISECT_LOG"):
+		return OpBisect
+	}
+	// A --no-commit pick or revert that stopped leaves only the todo
+	switch command, _ := sequencerHead(gitDir); command {
+	case "pick", "p":
+		return OpCherryPick
+	case "revert":
+		return OpRevert
+	}
+	return OpNone
+}
+
+// sequencerHead reads the next command of a multi-commit cherry-pick or
+// revert and the commit it applies
+func sequencerHead(gitDir string) (command, hash string) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "sequencer", "todo"))
+	if err != nil {
+		return "", ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && !strings.HasPrefix(fields[0], "#") {
+			return fields[0], fields[1]
+		}
+	}
+	return "", ""
+}
+
+// OperationState is the operation in progress with what the banner shows
+type OperationState struct {
+	Op        Operation
+	Head      string   // short hash being merged, picked or reverted
+	Subject   string   // its subject; the merge message for merges
+	Conflicts []string // unmerged paths
+}
+
+// GetOperationState reports the operation in progress and the commit it is
+// applying. Op is OpNone when the repository is idle.
+func GetOperationState(repoPath string) OperationState {
+	dir, err := gitDir(repoPath)
+	if err != nil {
+		return OperationState{}
+	}
+	state := OperationState{Op: operationIn(dir)}
+	if state.Op == OpNone {
+		return state
+	}
+
+	headFile := map[Operation]string{OpMerge: "MERGE_HEAD", OpCherryPick: "CHERRY_PICK_HEAD", OpRevert: "REVERT_HEAD"}[state.Op]
+	if headFile != "" {
+		data, err := os.ReadFile(filepath.Join(dir, headFile))
+		if err != nil && state.Op != OpMerge {
+			_, hash := sequencerHead(dir)
+			data, err = []byte(hash), nil
+		}
+		if err == nil {
+			// MERGE_HEAD lists one line per merged head (octopus)
+			head, _, _ := strings.Cut(strings.TrimSpace(string(data)), "\n")
+			cmd := exec.Command("git", "log", "-1", "--format=%h%x00%s", head)
+			cmd.Dir = repoPath
+			if output, err := cmd.Output(); err == nil {
+				state.Head, state.Subject, _ = strings.Cut(strings.TrimSpace(string(output)), "\x00")
+			}
+		}
+	}
+	if state.Op == OpMerge {
+		if data, err := os.ReadFile(filepath.Join(dir, "MERGE_MSG")); err == nil {
+			subject, _, _ := strings.Cut(string(data), "\n")
+			state.Subject = strings.TrimSpace(subject)
+		}
+	}
+
+	state.Conflicts = GetConflictFiles(repoPath)
+	return state
+}
+
+// ContinueOperation commits the resolved step and carries on
+func ContinueOperation(repoPath string, op Operation) error {
+	switch op {
+	case OpRebase, OpMerge, OpCherryPick, OpRevert:
+		return operationControl(repoPath, string(op), "--continue")
+	}
+	return fmt.Errorf("%s cannot be continued", op)
+}
+
+// SkipOperation drops the commit the operation stopped on; for bisect it
+// skips the commit being tested
+func SkipOperation(repoPath string, op Operation) error {
+	switch op {
+	case OpRebase, OpCherryPick, OpRevert:
+		return operationControl(repoPath, string(op), "--skip")
+	case OpBisect:
+		return operationControl(repoPath, "bisect", "skip")
+	}
+	return fmt.Errorf("%s cannot be skipped", op)
+}
+
+// AbortOperation restores the state from before the operation started
+func AbortOperation(repoPath string, op Operation) error {
+	switch op {
+	case OpRebase, OpMerge, OpCherryPick, OpRevert:
+		return operationControl(repoPath, string(op), "--abort")
+	case OpBisect:
+		return operationControl(repoPath, "bisect", "reset")
+	}
+	return fmt.Errorf("no operation in progress")
+}
+
+// operationControl runs a continue/skip/abort without letting git open an
+// editor, which can't share the terminal with the TUI; the commit message
+// git prepared is kept
+func operationControl(repoPath string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
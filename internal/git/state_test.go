@@ -0,0 +1,42 @@
+package git
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestOperationStateInWorktree runs a conflicting merge in a linked
+// worktree, where .git is a file, on a path git would C-quote
+func TestOperationStateInWorktree(t *testing.T) {
+	repo := newTestRepo(t)
+	writeFile(t, repo, "naïve file.txt", "base\n")
+	runGit(t, repo, "add", ".")
+	runGit(t, repo, "commit", "--quiet", "-m", "base")
+	runGit(t, repo, "branch", "other")
+	writeFile(t, repo, "naïve file.txt", "main\n")
+	runGit(t, repo, "commit", "--quiet", "-am", "main")
+
+	worktree := filepath.Join(t.TempDir(), "wt")
+	runGit(t, repo, "worktree", "add", "--quiet", worktree, "other")
+	writeFile(t, worktree, "naïve file.txt", "other\n")
+	runGit(t, worktree, "commit", "--quiet", "-am", "other")
+
+	cmd := exec.Command("git", "merge", "main")
+	cmd.Dir = worktree
+	if cmd.Run() == nil {
+		t.Fatal("expected the merge to conflict")
+	}
+
+	state := GetOperationState(worktree)
+	if state.Op != OpMerge {
+		t.Fatalf("Op = %q, want %q", state.Op, OpMerge)
+	}
+	if want := []string{"naïve file.txt"}; !reflect.DeepEqual(state.Conflicts, want) {
+		t.Errorf("Conflicts = %q, want %q", state.Conflicts, want)
+	}
+	if GetOperation(repo) != OpNone {
+		t.Errorf("the main worktree has no operation in progress")
+	}
+}
@@ -0,0 +1,466 @@
+package watcher
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/LFroesch/gitty/internal/logger"
+)
+
+var errTooManyDirs = errors.New("too many directories to watch")
+
+const (
+	debounceDelay   = 300 * time.Millisecond
+	pollInterval    = 2 * time.Second
+	maxPollInterval = 30 * time.Second // ceiling for the working tree scan in polling mode
+	maxWatchedDirs  = 4096             // fall back to polling above this many directories
+)
+
+// Event describes a debounced batch of filesystem changes
+type Event struct {
+	Worktree bool // working tree files changed
+	Git      bool // HEAD, index or refs changed
+}
+
+// Watcher watches a repository's working tree and .git metadata and reports
+// debounced change events. It uses inotify (via fsnotify) where possible and
+// falls back to polling when the tree is too large or watches are unavailable.
+type Watcher struct {
+	repoPath  string
+	gitDir    string // HEAD, index and operation state of this worktree
+	commonDir string // refs and packed-refs shared by all worktrees; gitDir outside linked worktrees
+	events    chan Event
+	done      chan struct{}
+	once      sync.Once
+	fsw       *fsnotify.Watcher
+}
+
+// New starts watching repoPath. Call Close to stop.
+func New(repoPath string) *Watcher {
+	w := &Watcher{
+		repoPath: repoPath,
+		events:   make(chan Event, 1),
+		done:     make(chan struct{}),
+	}
+	w.gitDir, w.commonDir = resolveGitDirs(repoPath)
+
+	fsw, err := w.startNotify()
+	if err != nil {
+		logger.Warn("watcher: falling back to polling: %v", err)
+		go w.poll()
+		return w
+	}
+
+	w.fsw = fsw
+	go w.run()
+	return w
+}
+
+// resolveGitDirs asks git for the repository's git directory and the
+// common directory holding its refs. They differ in linked worktrees, and
+// neither is .git where .git is a file (worktrees, submodules).
+func resolveGitDirs(repoPath string) (gitDir, commonDir string) {
+	gitDir = filepath.Join(repoPath, ".git")
+	cmd := exec.Command("git", "rev-parse", "--git-dir", "--git-common-dir")
+	cmd.Dir = repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return gitDir, gitDir
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) != 2 {
+		return gitDir, gitDir
+	}
+	abs := func(path string) string {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(repoPath, path)
+		}
+		return filepath.Clean(path)
+	}
+	return abs(lines[0]), abs(lines[1])
+}
+
+// Events returns the channel on which change events are delivered.
+// The channel is closed when the watcher is closed.
+func (w *Watcher) Events() <-chan Event {
+	return w.events
+}
+
+// Close stops the watcher
+func (w *Watcher) Close() {
+	w.once.Do(func() {
+		close(w.done)
+		if w.fsw != nil {
+			w.fsw.Close()
+		}
+	})
+}
+
+// inotify mode
+
+func (w *Watcher) startNotify() (*fsnotify.Watcher, error) {
+	dirs, err := w.worktreeDirs()
+	if err != nil {
+		return nil, err
+	}
+	if len(dirs) > maxWatchedDirs {
+		return nil, errTooManyDirs
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dir := range append(w.metadataDirs(), dirs...) {
+		if err := fsw.Add(dir); err != nil {
+			fsw.Close()
+			return nil, err
+		}
+	}
+
+	return fsw, nil
+}
+
+// metadataDirs lists the git directories to watch: HEAD and index live in
+// gitDir, branch tips under refs and packed-refs in the common directory
+func (w *Watcher) metadataDirs() []string {
+	roots := []string{w.gitDir}
+	if w.commonDir != w.gitDir {
+		roots = append(roots, w.commonDir)
+	}
+	var dirs []string
+	for _, root := range roots {
+		dirs = append(dirs, root)
+		filepath.WalkDir(filepath.Join(root, "refs"), func(path string, d fs.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				dirs = append(dirs, path)
+			}
+			return nil
+		})
+	}
+	return dirs
+}
+
+// worktreeDirs lists every directory that holds a tracked or untracked,
+// non-ignored file. Asking git keeps ignored trees like node_modules out of
+// the walk entirely.
+func (w *Watcher) worktreeDirs() ([]string, error) {
+	cmd := exec.Command("git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = w.repoPath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{w.repoPath: true}
+	dirs := []string{w.repoPath}
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file == "" {
+			continue
+		}
+		for dir := filepath.Dir(file); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+			abs := filepath.Join(w.repoPath, dir)
+			if seen[abs] {
+				break
+			}
+			seen[abs] = true
+			dirs = append(dirs, abs)
+		}
+		if len(dirs) > maxWatchedDirs {
+			break
+		}
+	}
+	return dirs, nil
+}
+
+func (w *Watcher) run() {
+	defer close(w.events)
+
+	var pending Event
+	var changed []string
+	timer := time.NewTimer(debounceDelay)
+	timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+
+		case ev, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if w.isGitPath(ev.Name) {
+				if !w.isInterestingGitPath(ev.Name) {
+					continue
+				}
+				pending.Git = true
+			} else {
+				if ev.Has(fsnotify.Create) {
+					w.watchNewDir(ev.Name)
+				}
+				changed = append(changed, ev.Name)
+			}
+			timer.Reset(debounceDelay)
+
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			logger.Warn("watcher: %v", err)
+
+		case <-timer.C:
+			if len(changed) > 0 {
+				ignored := w.ignoredPaths(changed)
+				for _, path := range changed {
+					if !ignored[path] {
+						pending.Worktree = true
+						break
+					}
+				}
+				changed = nil
+			}
+			if pending.Worktree || pending.Git {
+				w.send(pending)
+			}
+			pending = Event{}
+		}
+	}
+}
+
+// watchNewDir watches a created directory and every directory below it, as
+// a checkout or `mkdir -p` creates whole trees before we see the first event
+func (w *Watcher) watchNewDir(path string) {
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return
+	}
+
+	var dirs []string
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		dirs = append(dirs, p)
+		return nil
+	})
+
+	// Everything below an ignored directory is ignored too
+	ignored := w.ignoredPaths(dirs)
+	var skip []string
+	for _, dir := range dirs {
+		if ignored[dir] || slices.ContainsFunc(skip, func(s string) bool { return isWithin(dir, s) }) {
+			skip = append(skip, dir)
+			continue
+		}
+		if err := w.fsw.Add(dir); err != nil {
+			logger.Warn("watcher: cannot watch %s: %v", dir, err)
+		}
+	}
+}
+
+// isWithin reports whether path is dir or below it
+func isWithin(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+func (w *Watcher) isGitPath(path string) bool {
+	return isWithin(path, w.gitDir) || isWithin(path, w.commonDir)
+}
+
+// isInterestingGitPath filters out lock files and objects, which churn on
+// every git command and carry no state of their own
+func (w *Watcher) isInterestingGitPath(path string) bool {
+	if strings.HasSuffix(path, ".lock") {
+		return false
+	}
+	// A linked worktree's gitDir sits inside the common directory, which
+	// only contributes the shared refs
+	root := w.gitDir
+	if !isWithin(path, w.gitDir) {
+		root = w.commonDir
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.HasPrefix(rel, "refs/") || rel == "packed-refs" {
+		return true
+	}
+	if root != w.gitDir {
+		return false
+	}
+	switch rel {
+	case "HEAD", "index", "packed-refs", "ORIG_HEAD", "MERGE_HEAD",
+		"CHERRY_PICK_HEAD", "REVERT_HEAD", "FETCH_HEAD":
+		return true
+	}
+	return false
+}
+
+// Polling mode
+
+// poll checks the git metadata mtimes every tick. The working tree scan runs
+// git status, which is slow in exactly the huge repositories that end up
+// here, so it runs at most every treeInterval: ten times as long as the last
+// scan took, between pollInterval and maxPollInterval.
+func (w *Watcher) poll() {
+	defer close(w.events)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	lastGit := w.gitFingerprint()
+	start := time.Now()
+	lastTree := w.worktreeFingerprint()
+	treeInterval := scanInterval(time.Since(start))
+	nextTree := time.Now().Add(treeInterval)
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case now := <-ticker.C:
+			var ev Event
+			if fp := w.gitFingerprint(); fp != lastGit {
+				lastGit = fp
+				ev.Git = true
+			}
+			if !now.Before(nextTree) {
+				start := time.Now()
+				if fp := w.worktreeFingerprint(); fp != lastTree {
+					lastTree = fp
+					ev.Worktree = true
+				}
+				treeInterval = scanInterval(time.Since(start))
+				nextTree = time.Now().Add(treeInterval)
+			}
+			if ev.Git || ev.Worktree {
+				w.send(ev)
+			}
+		}
+	}
+}
+
+// scanInterval backs the working tree scan off to ten times its cost
+func scanInterval(took time.Duration) time.Duration {
+	return min(max(10*took, pollInterval), maxPollInterval)
+}
+
+// gitFingerprint summarizes HEAD, index and ref mtimes without spawning git
+func (w *Watcher) gitFingerprint() string {
+	var sb strings.Builder
+	files := []string{
+		filepath.Join(w.gitDir, "HEAD"),
+		filepath.Join(w.gitDir, "index"),
+		filepath.Join(w.commonDir, "packed-refs"),
+	}
+	for _, path := range files {
+		if info, err := os.Stat(path); err == nil {
+			sb.WriteString(info.ModTime().String())
+			sb.WriteByte(' ')
+		}
+	}
+	refDirs := []string{filepath.Join(w.gitDir, "refs")}
+	if w.commonDir != w.gitDir {
+		refDirs = append(refDirs, filepath.Join(w.commonDir, "refs"))
+	}
+	for _, dir := range refDirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				if info, err := d.Info(); err == nil {
+					sb.WriteString(path + info.ModTime().String())
+				}
+			}
+			return nil
+		})
+	}
+	return sb.String()
+}
+
+// worktreeFingerprint hashes git's view of the working tree, which already
+// honors .gitignore, plus the mtimes of the dirty files so further edits to an
+// already-modified file are noticed
+func (w *Watcher) worktreeFingerprint() [sha1.Size]byte {
+	cmd := exec.Command("git", "--no-optional-locks", "status", "--porcelain", "-z")
+	cmd.Dir = w.repoPath
+	output, _ := cmd.Output()
+
+	h := sha1.New()
+	h.Write(output)
+	for _, entry := range strings.Split(string(output), "\x00") {
+		if len(entry) < 4 {
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(w.repoPath, entry[3:])); err == nil {
+			h.Write([]byte(info.ModTime().String()))
+		}
+	}
+
+	var sum [sha1.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// Shared helpers
+
+// send delivers an event, merging with any event the consumer hasn't read yet
+func (w *Watcher) send(ev Event) {
+	for {
+		select {
+		case <-w.done:
+			return
+		case w.events <- ev:
+			return
+		case prev := <-w.events:
+			ev.Git = ev.Git || prev.Git
+			ev.Worktree = ev.Worktree || prev.Worktree
+		}
+	}
+}
+
+// ignoredPaths batches paths through git check-ignore and returns the ignored set
+func (w *Watcher) ignoredPaths(paths []string) map[string]bool {
+	ignored := make(map[string]bool)
+	if len(paths) == 0 {
+		return ignored
+	}
+
+	var input bytes.Buffer
+	for _, p := range paths {
+		input.WriteString(p)
+		input.WriteByte(0)
+	}
+
+	cmd := exec.Command("git", "check-ignore", "--stdin", "-z")
+	cmd.Dir = w.repoPath
+	cmd.Stdin = &input
+	output, _ := cmd.Output() // exits 1 when nothing is ignored
+
+	for _, p := range strings.Split(string(output), "\x00") {
+		if p == "" {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(w.repoPath, p)
+		}
+		ignored[p] = true
+	}
+	return ignored
+}
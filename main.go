@@ -17,6 +17,13 @@ func main() {
 	}
 	defer logger.Close()
 
+	// Non-interactive subcommands
+	if len(os.Args) > 1 {
+		code := runCLI(os.Args[1:], os.Stdout, os.Stderr)
+		logger.Close()
+		os.Exit(code)
+	}
+
 	// Check if we're in a git repo
 	cwd, _ := os.Getwd()
 	if !git.IsRepo(cwd) {
@@ -7,7 +7,10 @@ import (
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/LFroesch/gitty/internal/config"
 	"github.com/LFroesch/gitty/internal/git"
+	"github.com/LFroesch/gitty/internal/logger"
+	"github.com/LFroesch/gitty/internal/watcher"
 )
 
 // Constants
@@ -27,12 +30,26 @@ type gitChangesMsg []git.Change
 type commitSuggestionsMsg []CommitSuggestion
 type gitStatusMsg git.Status
 type branchesMsg []git.Branch
-type commitsMsg []git.Commit
 type recentCommitsMsg []git.Commit
 type diffMsg string
 type conflictsMsg []git.ConflictFile
 type comparisonMsg git.BranchComparison
+type rebasePredictionMsg git.MergePrediction
+type compareDiffMsg struct {
+	path string
+	text string
+}
 type rebaseCommitsMsg []git.RebaseCommit
+type rebaseStateMsg struct{ state *git.RebaseState }
+type operationMsg git.OperationState
+type bisectStateMsg struct{ state *git.BisectState }
+type bisectRunMsg struct{ run *git.BisectRun }
+type bisectOutputMsg string
+type bisectRunDoneMsg struct{ err error }
+type cherryPickMsg struct {
+	message string
+	stopped bool // left in progress on a conflict
+}
 type pushOutputMsg struct {
 	output string
 	commit string
@@ -43,28 +60,66 @@ type commitSuccessMsg struct {
 	diff    string
 	files   []string
 }
+type resetPreviewMsg git.ResetPreview
+type reflogMsg struct {
+	ref     string
+	entries []git.ReflogEntry
+}
 type stashListMsg []git.Stash
 type tagListMsg []git.Tag
 type hookStatusMsg bool
 type preCommitHookMsg bool
 type stashDiffMsg string
-type logCommitsMsg []git.Commit
+type commitPageMsg struct {
+	target  string // "history" (undo view) or "log"
+	query   git.LogQuery
+	skip    int
+	commits []git.Commit
+}
 type logDetailMsg git.CommitDetail
 type logDiffMsg string
-type blameMsg []git.BlameLine
+type blameMsg struct {
+	lines      []git.BlameLine
+	syntax     [][]span
+	ignoreRevs string
+	cursor     int // line to select once loaded
+}
+type blameMessageMsg struct {
+	hash    string
+	message string
+}
+
+// blameFrame is a blame position to return to after walking back in history
+type blameFrame struct {
+	rev    string
+	file   string
+	cursor int
+}
+
+type fileHistoryMsg struct {
+	query     git.LogQuery // Rev pinned, Paths holds the followed file
+	skip      int
+	revisions []git.FileRevision
+}
+type fileRevisionMsg struct {
+	show   string // "diff" or "content"
+	text   string
+	syntax [][]span // highlighted content lines
+}
 type cloneResultMsg struct {
 	output  string
 	err     error
 	newPath string
 }
 type repoSwitchMsg string
+type repoChangedMsg watcher.Event
 
 // Model
 
 type model struct {
 	// State management
 	tab         string // "workspace", "commit", "branches", "tools"
-	toolMode    string // when tab="tools": "menu", "undo", "rebase", "history", "remote", "stash", "tags", "hooks"
+	toolMode    string // when tab="tools": "menu", "undo", "rebase", "reflog", "remote", "stash", "tags", "hooks", "operation", "bisect", "cherrypick"
 	toolSubmenu string // "local", "remote", "history", "advanced", "hooks"
 	viewMode    string // workspace sub-states: "files", "diff", "conflicts"
 
@@ -74,9 +129,27 @@ type model struct {
 	gitState         git.Status
 	branches         []git.Branch
 	commits          []git.Commit
+	commitsPager     commitPager
 	conflicts        []git.ConflictFile
 	branchComparison *git.BranchComparison
 	rebaseCommits    []git.RebaseCommit
+	rebasePrediction *git.MergePrediction // dry run of the current plan
+	rebaseOpts       git.RebaseOptions
+	rebaseUnsquashed []git.RebaseCommit // plan before autosquash, to move its commits back when it's turned off
+	rebaseState      *git.RebaseState   // stopped rebase, nil when none is in progress
+	operation        git.OperationState // merge, cherry-pick, revert, rebase or bisect waiting on the user
+	bisectState      *git.BisectState   // nil when no bisect is running
+	bisectRun        *git.BisectRun     // automated `bisect run` in progress
+	bisectOutput     []string           // output of the last bisect run
+	bisectBad        string             // commits picked in the log to start a bisect
+	bisectGood       string
+	pickMarks        map[string]bool // commits marked in the log to cherry-pick
+	pickAnchor       int             // log index last marked, where a range mark starts
+	pickCommits      []git.Commit    // cherry-pick queue, oldest first
+	pickSelected     []bool          // which of pickCommits will be picked
+	pickOpts         git.CherryPickOptions
+	pickReturn       string // "log", "compare" or "reflog": where the cherry-pick panel was opened
+	rebaseChecked    bool   // rebaseState has been loaded once
 
 	// UI content
 	diffContent   string
@@ -85,23 +158,29 @@ type model struct {
 	commitSummary *commitSuccessMsg
 
 	// List navigation (replaces tables)
-	fileCursor     int
-	fileOffset     int
-	branchCursor   int
-	branchOffset   int
-	toolCursor     int
-	historyCursor  int
-	historyOffset  int
-	conflictCursor int
-	compareCursor  int
-	rebaseCursor   int
-	undoCursor     int
-	undoOffset     int
+	fileCursor      int
+	fileOffset      int
+	branchCursor    int
+	branchOffset    int
+	toolCursor      int
+	conflictCursor  int
+	compareCursor   int // file in the ref comparison
+	compareOffset   int
+	rebaseCursor    int
+	rebaseOffset    int
+	rebaseConflicts bool // predicted conflicts shown instead of the plan
+	undoCursor      int
+	undoOffset      int
+	pickCursor      int
+	pickOffset      int
 
 	// Inputs
 	commitInput textinput.Model
 	branchInput textinput.Model
 	rebaseInput textinput.Model
+	rebaseEdit  textinput.Model // inline reword message, exec command or onto ref
+	rebaseField string          // what rebaseEdit edits: "reword", "exec", "onto"
+	bisectInput textinput.Model // command for bisect run
 
 	// UI state
 	width              int
@@ -109,13 +188,33 @@ type model struct {
 	statusMessage      string
 	statusExpiry       time.Time
 	showDiffPreview    bool
+	diffSplit          bool // side-by-side diff layout
+	diffOpts           git.DiffOptions
+	diffChange         git.Change // change shown in the preview / full diff
+	diffLarge          bool       // diffContent is over the render limits
+	diffShowLarge      bool       // user chose to render it anyway
 	selectedSuggestion int
 	scrollOffset       int
 
+	// Undo
+	resetMode    git.ResetMode
+	resetPreview *git.ResetPreview // for the selected commit, nil while loading
+
 	// Stash
 	stashes     []git.Stash
 	stashCursor int
 	stashOffset int
+	stashOpts   git.StashOptions // flags for the next push; Message and Paths unused
+	stashInput  textinput.Model  // stash message, new name or branch name
+	stashField  string           // what stashInput is for: "message", "rename", "branch"
+	stashMarks  map[string]bool  // workspace files marked for a path-limited stash
+
+	// Reflog
+	reflog       []git.ReflogEntry
+	reflogRef    string // "HEAD" or a local branch
+	reflogCursor int
+	reflogOffset int
+	reflogInput  textinput.Model // name of a branch to create at the selected entry
 
 	// Tags
 	tags      []git.Tag
@@ -134,18 +233,49 @@ type model struct {
 
 	// Log viewer
 	logCommits     []git.Commit
+	logGraph       []graphRow    // one row per logCommits entry, nil when filtered
+	logGraphLanes  *graphBuilder // lane state carried across pages
+	logPager       commitPager
 	logCursor      int
 	logOffset      int
 	logSearch      string
 	logSearchInput textinput.Model
+	logFilter      git.LogQuery // author/date/path/content filters, Search and Rev unset
+	logFilterInput textinput.Model
+	logSavePreset  bool   // filter input is asking for a preset name
+	logPreset      string // name of the applied preset
 	logDetail      *git.CommitDetail
 	logDiff        string
 
+	// File history
+	fileLogPath   string // path as of HEAD
+	fileLogRevs   []git.FileRevision
+	fileLogPager  commitPager
+	fileLogCursor int
+	fileLogOffset int
+	fileLogShow   string // "diff" or "content" while viewing one revision
+	fileLogText   string
+	fileLogSyntax [][]span // highlighted lines when showing content
+	fileLogReturn string   // workspace viewMode to go back to
+
 	// Blame
-	blameLines  []git.BlameLine
-	blameCursor int
-	blameOffset int
-	blameFile   string
+	blameLines      []git.BlameLine
+	blameSyntax     [][]span // highlighted content, nil when not highlighted
+	blameCursor     int
+	blameOffset     int
+	blameFile       string
+	blameOpts       git.BlameOptions // Rev is "" for the working tree
+	blameStack      []blameFrame     // where "blame parent" came from
+	blameIgnoreRevs string
+	blameMessages   map[string]string // full commit messages by hash
+
+	logDetailReturn string // "blame", "bisect" or "reflog" when the log detail was opened from there
+
+	// Ref comparison (Branches tab)
+	compareInput     textinput.Model // "target..source" / "target...source"
+	compareDiffPath  string          // file whose diff is open, "" for the list
+	compareDiff      string
+	compareConflicts bool // predicted merge conflicts are open
 
 	// Clone/Init
 	cloneInput textinput.Model
@@ -156,6 +286,8 @@ type model struct {
 	lastCommit       string
 	lastStatusUpdate time.Time
 	confirmAction    string
+	watcher          *watcher.Watcher
+	config           config.Config
 }
 
 // Styles
@@ -244,6 +376,13 @@ var (
 			Foreground(lipgloss.Color("214")).
 			Bold(true)
 
+	// In-progress operation banner in the top bar
+	operationBannerStyle = lipgloss.NewStyle().
+				Padding(0, 1).
+				Foreground(lipgloss.Color("232")).
+				Background(lipgloss.Color("214")).
+				Bold(true)
+
 	// Scroll indicators
 	scrollIndicatorStyle = lipgloss.NewStyle().
 				Foreground(lipgloss.Color("245")).
@@ -336,9 +475,27 @@ func initialModel() model {
 	branchInput.Placeholder = "Branch name..."
 	branchInput.CharLimit = 100
 
+	compareInput := textinput.New()
+	compareInput.Placeholder = "main..feature, v1.0...HEAD or a single ref to compare with HEAD"
+	compareInput.CharLimit = 200
+
 	rebaseInput := textinput.New()
 	rebaseInput.Placeholder = "Number of commits to rebase..."
-	rebaseInput.CharLimit = 3
+	rebaseInput.CharLimit = 5
+
+	rebaseEdit := textinput.New()
+	rebaseEdit.CharLimit = 500
+
+	bisectInput := textinput.New()
+	bisectInput.Placeholder = "Test command: exit 0 = good, 125 = skip, other = bad (e.g. go test ./...)"
+	bisectInput.CharLimit = 500
+
+	reflogInput := textinput.New()
+	reflogInput.Placeholder = "Branch name..."
+	reflogInput.CharLimit = 100
+
+	stashInput := textinput.New()
+	stashInput.CharLimit = 200
 
 	tagInput := textinput.New()
 	tagInput.Placeholder = "Tag name (e.g. v1.0.0)..."
@@ -348,6 +505,18 @@ func initialModel() model {
 	logSearchInput.Placeholder = "Search commits..."
 	logSearchInput.CharLimit = 100
 
+	logFilterInput := textinput.New()
+	logFilterInput.Placeholder = `author:name since:"2 weeks ago" path:src/ S:text no-merges`
+	logFilterInput.CharLimit = 300
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("load config: %v", err)
+	}
+	if !setSyntaxTheme(cfg.SyntaxTheme) {
+		logger.Error("unknown syntax_theme %q, using default", cfg.SyntaxTheme)
+	}
+
 	cloneInput := textinput.New()
 	cloneInput.Placeholder = "Repository URL (https://... or git@...)..."
 	cloneInput.CharLimit = 200
@@ -364,14 +533,24 @@ func initialModel() model {
 		repoPath:               repoPath,
 		commitInput:            commitInput,
 		branchInput:            branchInput,
+		compareInput:           compareInput,
 		rebaseInput:            rebaseInput,
+		rebaseEdit:             rebaseEdit,
+		bisectInput:            bisectInput,
+		reflogInput:            reflogInput,
+		reflogRef:              "HEAD",
+		resetMode:              git.ResetSoft,
+		stashInput:             stashInput,
 		tagInput:               tagInput,
 		logSearchInput:         logSearchInput,
+		logFilterInput:         logFilterInput,
+		config:                 cfg,
 		cloneInput:             cloneInput,
 		initInput:              initInput,
 		showDiffPreview:        true,
 		selectedSuggestion:     0,
 		commitMsgHookInstalled: git.IsCommitMsgHookInstalled(repoPath),
 		preCommitHookInstalled: git.IsPreCommitHookInstalled(repoPath),
+		watcher:                watcher.New(repoPath),
 	}
 }
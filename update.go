@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/LFroesch/gitty/internal/git"
+	"github.com/LFroesch/gitty/internal/watcher"
 )
 
 func (m model) Init() tea.Cmd {
@@ -16,6 +18,7 @@ func (m model) Init() tea.Cmd {
 		m.loadGitChanges(),
 		m.loadGitStatus(),
 		m.loadRecentCommits(),
+		m.waitForRepoChange(),
 	)
 }
 
@@ -38,6 +41,12 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case gitChangesMsg:
 		m.changes = msg
+		// Marks on files that no longer have changes go away
+		for file := range m.stashMarks {
+			if !slices.ContainsFunc(m.changes, func(c git.Change) bool { return c.File == file }) {
+				delete(m.stashMarks, file)
+			}
+		}
 		// Adjust cursor if needed
 		if m.fileCursor >= len(m.changes) {
 			m.fileCursor = max(0, len(m.changes)-1)
@@ -46,7 +55,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds = append(cmds, m.generateCommitSuggestions())
 		// Load diff for selected file
 		if len(m.changes) > 0 && m.fileCursor < len(m.changes) {
-			cmds = append(cmds, m.loadFileDiff(m.changes[m.fileCursor].File))
+			cmds = append(cmds, m.loadFileDiff(m.changes[m.fileCursor]))
+		} else {
+			m.diffChange = git.Change{}
 		}
 		return m, tea.Batch(cmds...)
 
@@ -61,8 +72,60 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
-	case commitsMsg:
-		m.commits = msg
+	case commitPageMsg:
+		switch msg.target {
+		case "history":
+			if msg.skip == 0 {
+				m.commits = nil
+				m.commitsPager = commitPager{query: msg.query}
+			} else if msg.skip != len(m.commits) || !msg.query.Equal(m.commitsPager.query) {
+				return m, nil // stale page from before a reload
+			}
+			m.commits = append(m.commits, msg.commits...)
+			m.commitsPager.loading = false
+			m.commitsPager.done = len(msg.commits) < historyPageSize
+			m.undoCursor = min(m.undoCursor, max(0, len(m.commits)-1))
+			if msg.skip == 0 && m.tab == "tools" && m.toolMode == "undo" {
+				return m, m.loadResetPreview()
+			}
+
+		case "log":
+			current := msg.query
+			current.Rev = ""
+			if !current.Equal(m.logQuery()) {
+				return m, nil // search or filter changed while loading
+			}
+			if msg.skip == 0 {
+				m.logCommits = nil
+				m.logGraph = nil
+				m.logPager = commitPager{query: msg.query}
+				// Filters break parent links, so filtered logs stay flat
+				m.logGraphLanes = nil
+				if !msg.query.Filtered() {
+					m.logGraphLanes = &graphBuilder{}
+				}
+			} else if msg.skip != len(m.logCommits) || !msg.query.Equal(m.logPager.query) {
+				return m, nil
+			}
+			m.logCommits = append(m.logCommits, msg.commits...)
+			// Lanes are laid out once per page so scrolling only renders visible rows
+			if m.logGraphLanes != nil {
+				for _, c := range msg.commits {
+					m.logGraph = append(m.logGraph, m.logGraphLanes.add(c))
+				}
+			}
+			m.logPager.loading = false
+			m.logPager.done = len(msg.commits) < historyPageSize
+			m.logCursor = min(m.logCursor, max(0, len(m.logCommits)-1))
+		}
+		return m, nil
+
+	case resetPreviewMsg:
+		preview := git.ResetPreview(msg)
+		// Drop previews for a commit the cursor has already left
+		if m.undoCursor < len(m.commits) && m.commits[m.undoCursor].Hash == preview.Target {
+			m.resetPreview = &preview
+		}
 		return m, nil
 
 	case recentCommitsMsg:
@@ -71,6 +134,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case diffMsg:
 		m.diffContent = string(msg)
+		m.diffLarge = isLargeDiff(m.diffContent)
 		return m, nil
 
 	case conflictsMsg:
@@ -80,10 +144,121 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case comparisonMsg:
 		comparison := git.BranchComparison(msg)
 		m.branchComparison = &comparison
+		m.compareCursor = 0
+		m.compareOffset = 0
+		m.compareDiffPath = ""
+		m.compareConflicts = false
+		return m, nil
+
+	case rebasePredictionMsg:
+		prediction := git.MergePrediction(msg)
+		m.rebasePrediction = &prediction
+		m.confirmAction = "rebase"
+		switch {
+		case prediction.Error != "":
+			m.statusMessage = "Could not predict conflicts (" + prediction.Error + "). Press enter again to rebase anyway"
+		case !prediction.Clean:
+			m.statusMessage = fmt.Sprintf("Rebase would stop at %s with %d conflicting files (x: details). Press enter again to rebase anyway",
+				git.ShortHash(prediction.Commit), len(prediction.Conflicts))
+		default:
+			m.statusMessage = "No conflicts predicted. Press enter again to execute rebase (rewrites history!)"
+		}
+		return m, nil
+
+	case compareDiffMsg:
+		m.compareDiffPath = msg.path
+		m.compareDiff = msg.text
+		m.scrollOffset = 0
+		return m, nil
+
+	case operationMsg:
+		m.operation = git.OperationState(msg)
+		if m.toolMode == "operation" && (m.operation.Op == git.OpNone || m.operation.Op == git.OpRebase || m.operation.Op == git.OpBisect) {
+			m.toolMode = "menu"
+		}
+		return m, nil
+
+	case reflogMsg:
+		if msg.ref != m.reflogRef {
+			m.reflogCursor = 0
+			m.reflogOffset = 0
+		}
+		m.reflogRef = msg.ref
+		m.reflog = msg.entries
+		m.reflogCursor = min(m.reflogCursor, max(0, len(m.reflog)-1))
+		return m, nil
+
+	case cherryPickMsg:
+		m.pickMarks = nil
+		status := func() tea.Msg { return statusMsg{message: msg.message} }
+		if msg.stopped {
+			m.pickCommits, m.pickSelected = nil, nil
+			m.tab = "tools"
+			m.toolMode = "operation"
+			return m, status
+		}
+		return m, tea.Batch(m.leaveCherryPick(), status)
+
+	case bisectStateMsg:
+		found := msg.state != nil && msg.state.FirstBad != nil && (m.bisectState == nil || m.bisectState.FirstBad == nil)
+		m.bisectState = msg.state
+		if found && m.tab == "tools" && m.toolMode == "bisect" && m.bisectRun == nil {
+			return m, m.showFirstBadCommit()
+		}
+		return m, nil
+
+	case bisectRunMsg:
+		m.bisectRun = msg.run
+		m.bisectOutput = nil
+		return m, waitForBisectOutput(msg.run)
+
+	case bisectOutputMsg:
+		m.bisectOutput = append(m.bisectOutput, string(msg))
+		if m.bisectRun == nil {
+			return m, nil
+		}
+		return m, waitForBisectOutput(m.bisectRun)
+
+	case bisectRunDoneMsg:
+		m.bisectRun = nil
+		m.statusMessage = "Bisect run finished"
+		if msg.err != nil {
+			m.statusMessage = fmt.Sprintf("Bisect run stopped: %v", msg.err)
+		}
+		return m, tea.Batch(m.loadBisectState(), m.loadGitChanges(), m.loadGitStatus())
+
+	case rebaseStateMsg:
+		started := msg.state != nil && m.rebaseState == nil
+		finished := msg.state == nil && m.rebaseState != nil
+		startup := !m.rebaseChecked
+		m.rebaseState = msg.state
+		m.rebaseChecked = true
+		switch {
+		case started && startup:
+			// Opened on a stopped rebase: go straight to the panel
+			m.tab = "tools"
+			m.toolMode = "rebase"
+			m.statusMessage = rebaseStoppedMessage(msg.state)
+		case started && !(m.tab == "tools" && m.toolMode == "rebase"):
+			m.statusMessage = rebaseStoppedMessage(msg.state) + " (Tools > r)"
+		case finished && m.toolMode == "rebase":
+			// The plan that was executed is history now
+			m.rebaseCommits = nil
+			m.rebasePrediction = nil
+			m.rebaseInput.SetValue("")
+			m.rebaseInput.Focus()
+			return m, textinput.Blink
+		}
 		return m, nil
 
 	case rebaseCommitsMsg:
 		m.rebaseCommits = msg
+		m.rebaseCursor = 0
+		m.rebaseOffset = 0
+		m.rebasePrediction = nil
+		m.rebaseConflicts = false
+		m.rebaseOpts = git.RebaseOptions{}
+		m.rebaseUnsquashed = nil
 		return m, nil
 
 	case pushOutputMsg:
@@ -127,13 +302,6 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.diffContent = string(msg)
 		return m, nil
 
-	case logCommitsMsg:
-		m.logCommits = msg
-		if m.logCursor >= len(m.logCommits) {
-			m.logCursor = max(0, len(m.logCommits)-1)
-		}
-		return m, nil
-
 	case logDetailMsg:
 		detail := git.CommitDetail(msg)
 		m.logDetail = &detail
@@ -143,10 +311,42 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.logDiff = string(msg)
 		return m, nil
 
+	case fileHistoryMsg:
+		if len(msg.query.Paths) == 0 || msg.query.Paths[0] != m.fileLogPath {
+			return m, nil // a different file was opened meanwhile
+		}
+		if msg.skip == 0 {
+			m.fileLogRevs = nil
+			m.fileLogPager = commitPager{query: msg.query}
+		} else if msg.skip != len(m.fileLogRevs) || !msg.query.Equal(m.fileLogPager.query) {
+			return m, nil
+		}
+		m.fileLogRevs = append(m.fileLogRevs, msg.revisions...)
+		m.fileLogPager.loading = false
+		m.fileLogPager.done = len(msg.revisions) < historyPageSize
+		return m, nil
+
+	case fileRevisionMsg:
+		m.fileLogShow = msg.show
+		m.fileLogText = msg.text
+		m.fileLogSyntax = msg.syntax
+		m.scrollOffset = 0
+		return m, nil
+
 	case blameMsg:
-		m.blameLines = msg
-		m.blameCursor = 0
+		m.blameLines = msg.lines
+		m.blameSyntax = msg.syntax
+		m.blameIgnoreRevs = msg.ignoreRevs
+		m.blameCursor = max(0, min(msg.cursor, len(m.blameLines)-1))
 		m.blameOffset = 0
+		m.adjustBlameScroll()
+		return m, m.loadBlameMessage()
+
+	case blameMessageMsg:
+		if m.blameMessages == nil {
+			m.blameMessages = map[string]string{}
+		}
+		m.blameMessages[msg.hash] = msg.message
 		return m, nil
 
 	case cloneResultMsg:
@@ -161,6 +361,24 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.cleanCursor = 0
 		return m, nil
 
+	case repoChangedMsg:
+		cmds = append(cmds, m.waitForRepoChange(), m.loadGitChanges(), m.loadGitStatus())
+		if msg.Git {
+			cmds = append(cmds, m.loadRecentCommits())
+			if m.tab == "branches" && m.branchComparison == nil {
+				cmds = append(cmds, m.loadBranches())
+			}
+		}
+		// The reset preview depends on the working tree as well as history
+		if m.tab == "tools" && m.toolMode == "undo" {
+			if msg.Git {
+				cmds = append(cmds, m.loadCommitHistory())
+			} else {
+				cmds = append(cmds, m.loadResetPreview())
+			}
+		}
+		return m, tea.Batch(cmds...)
+
 	case repoSwitchMsg:
 		newPath := string(msg)
 		m.repoPath = newPath
@@ -173,11 +391,17 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.diffContent = ""
 		m.commitMsgHookInstalled = git.IsCommitMsgHookInstalled(newPath)
 		m.preCommitHookInstalled = git.IsPreCommitHookInstalled(newPath)
+		// Watch the new repo instead of the old one
+		if m.watcher != nil {
+			m.watcher.Close()
+		}
+		m.watcher = watcher.New(newPath)
 		// Reload everything
 		return m, tea.Batch(
 			m.loadGitChanges(),
 			m.loadGitStatus(),
 			m.loadRecentCommits(),
+			m.waitForRepoChange(),
 			func() tea.Msg { return statusMsg{message: "Switched to " + newPath} },
 		)
 	}
@@ -198,11 +422,36 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.rebaseInput, cmd = m.rebaseInput.Update(msg)
 		cmds = append(cmds, cmd)
 	}
+	if m.rebaseEdit.Focused() {
+		var cmd tea.Cmd
+		m.rebaseEdit, cmd = m.rebaseEdit.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	if m.bisectInput.Focused() {
+		var cmd tea.Cmd
+		m.bisectInput, cmd = m.bisectInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	if m.stashInput.Focused() {
+		var cmd tea.Cmd
+		m.stashInput, cmd = m.stashInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+	if m.reflogInput.Focused() {
+		var cmd tea.Cmd
+		m.reflogInput, cmd = m.reflogInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
 	if m.logSearchInput.Focused() {
 		var cmd tea.Cmd
 		m.logSearchInput, cmd = m.logSearchInput.Update(msg)
 		cmds = append(cmds, cmd)
 	}
+	if m.logFilterInput.Focused() {
+		var cmd tea.Cmd
+		m.logFilterInput, cmd = m.logFilterInput.Update(msg)
+		cmds = append(cmds, cmd)
+	}
 	if m.cloneInput.Focused() {
 		var cmd tea.Cmd
 		m.cloneInput, cmd = m.cloneInput.Update(msg)
@@ -220,6 +469,14 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
 
+	// Log search, filter, rebase, bisect and reflog text may contain q or digits
+	if m.tab == "tools" && key != "ctrl+c" && (m.logInputFocused() || m.rebaseInputFocused() || m.bisectInput.Focused() || m.reflogInput.Focused() || m.stashInput.Focused()) {
+		return m.handleToolsKey(key, msg)
+	}
+	if m.tab == "branches" && key != "ctrl+c" && (m.branchInput.Focused() || m.compareInput.Focused()) {
+		return m.handleBranchesKey(key, msg)
+	}
+
 	// Global keys
 	switch key {
 	case "ctrl+c", "q":
@@ -240,6 +497,39 @@ func (m model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.tab = "tools"
 		m.toolMode = "menu"
 		return m, nil
+	case "O":
+		// Actions for the operation in the banner
+		if m.operation.Op != git.OpNone && !m.textInputFocused() {
+			m.tab = "tools"
+			m.confirmAction = ""
+			switch m.operation.Op {
+			case git.OpRebase:
+				m.toolMode = "rebase"
+				return m, m.loadRebaseState()
+			case git.OpBisect:
+				m.toolMode = "bisect"
+				return m, m.loadBisectState()
+			}
+			m.toolMode = "operation"
+			return m, m.loadOperation()
+		}
+	}
+
+	if key == "U" && !m.textInputFocused() {
+		// Undo the last destructive action from the journal
+		entry, ok := git.LastUndoable(m.repoPath)
+		if !ok {
+			m.statusMessage = "Nothing to undo"
+			m.statusExpiry = time.Now().Add(3 * time.Second)
+			return m, nil
+		}
+		if m.confirmAction != "undo-last" {
+			m.confirmAction = "undo-last"
+			m.statusMessage = fmt.Sprintf("Press 'U' again to undo: %s (%s)", entry.Action, entry.Time.Format("15:04"))
+			return m, nil
+		}
+		m.confirmAction = ""
+		return m, m.undoLastAction()
 	}
 
 	// Tab-specific keys
@@ -257,11 +547,59 @@ func (m model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
-	if m.viewMode == "diff" {
+func (m model) handleBlameKey(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "esc":
+		// Step back out of "blame parent" before leaving blame
+		if n := len(m.blameStack); n > 0 {
+			frame := m.blameStack[n-1]
+			m.blameStack = m.blameStack[:n-1]
+			m.blameOpts.Rev = frame.rev
+			m.blameFile = frame.file
+			return m, m.reloadBlame(frame.file, frame.cursor)
+		}
+		m.viewMode = "files"
+		m.blameLines = nil
+		m.blameOpts = git.BlameOptions{}
+		return m, nil
+	case "h":
+		return m, m.openFileHistory(m.blameFile)
+	case "j", "down":
+		if m.blameCursor < len(m.blameLines)-1 {
+			m.blameCursor++
+			m.adjustBlameScroll()
+		}
+		return m, m.loadBlameMessage()
+	case "k", "up":
+		if m.blameCursor > 0 {
+			m.blameCursor--
+			m.adjustBlameScroll()
+		}
+		return m, m.loadBlameMessage()
+	case "enter":
+		return m, m.openBlameCommit()
+	case "p":
+		return m, m.blameParent()
+	case "w":
+		m.blameOpts.IgnoreWhitespace = !m.blameOpts.IgnoreWhitespace
+		return m, m.reloadBlame(m.blameFile, m.blameCursor)
+	case "m":
+		m.blameOpts.DetectMoves = !m.blameOpts.DetectMoves
+		return m, m.reloadBlame(m.blameFile, m.blameCursor)
+	case "c":
+		m.blameOpts.DetectCopies = !m.blameOpts.DetectCopies
+		return m, m.reloadBlame(m.blameFile, m.blameCursor)
+	}
+	return m, nil
+}
+
+func (m model) handleFileHistoryKey(key string) (tea.Model, tea.Cmd) {
+	// Viewing one revision
+	if m.fileLogShow != "" {
 		switch key {
 		case "esc":
-			m.viewMode = "files"
+			m.fileLogShow = ""
+			m.fileLogText = ""
 			return m, nil
 		case "j", "down":
 			m.scrollOffset++
@@ -271,32 +609,87 @@ func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
 				m.scrollOffset--
 			}
 			return m, nil
+		case "t":
+			m.diffSplit = !m.diffSplit
+			return m, nil
+		case "d", "o":
+			// Switch between diff and content of the same revision
+			if m.fileLogCursor < len(m.fileLogRevs) {
+				show := "diff"
+				if key == "o" {
+					show = "content"
+				}
+				return m, m.loadFileRevision(m.fileLogRevs[m.fileLogCursor], show)
+			}
 		}
 		return m, nil
 	}
 
-	if m.viewMode == "blame" {
+	switch key {
+	case "esc":
+		m.viewMode = m.fileLogReturn
+		m.fileLogRevs = nil
+		return m, nil
+	case "j", "down":
+		if m.fileLogCursor < len(m.fileLogRevs)-1 {
+			m.fileLogCursor++
+			m.adjustFileLogScroll()
+		}
+		return m, m.loadMoreCommits("file", m.fileLogCursor)
+	case "k", "up":
+		if m.fileLogCursor > 0 {
+			m.fileLogCursor--
+			m.adjustFileLogScroll()
+		}
+		return m, nil
+	case "enter", "d":
+		if m.fileLogCursor < len(m.fileLogRevs) {
+			return m, m.loadFileRevision(m.fileLogRevs[m.fileLogCursor], "diff")
+		}
+		return m, nil
+	case "o":
+		if m.fileLogCursor < len(m.fileLogRevs) {
+			return m, m.loadFileRevision(m.fileLogRevs[m.fileLogCursor], "content")
+		}
+		return m, nil
+	}
+	return m, nil
+}
+
+func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
+	if m.viewMode == "diff" {
 		switch key {
 		case "esc":
 			m.viewMode = "files"
-			m.blameLines = nil
+			return m, nil
+		case "t":
+			m.diffSplit = !m.diffSplit
+			return m, nil
+		case "i", "+", "-", "M":
+			return m.toggleDiffOption(key)
+		case "y":
+			m.diffShowLarge = true
 			return m, nil
 		case "j", "down":
-			if m.blameCursor < len(m.blameLines)-1 {
-				m.blameCursor++
-				m.adjustBlameScroll()
-			}
+			m.scrollOffset++
 			return m, nil
 		case "k", "up":
-			if m.blameCursor > 0 {
-				m.blameCursor--
-				m.adjustBlameScroll()
+			if m.scrollOffset > 0 {
+				m.scrollOffset--
 			}
 			return m, nil
 		}
 		return m, nil
 	}
 
+	if m.viewMode == "filelog" {
+		return m.handleFileHistoryKey(key)
+	}
+
+	if m.viewMode == "blame" {
+		return m.handleBlameKey(key)
+	}
+
 	if m.viewMode == "conflicts" {
 		switch key {
 		case "esc":
@@ -317,7 +710,9 @@ func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
 			// Open conflict file in diff view
 			if m.conflictCursor < len(m.conflicts) {
 				m.viewMode = "diff"
-				return m, m.loadFileDiff(m.conflicts[m.conflictCursor].Path)
+				conflict := git.Change{File: m.conflicts[m.conflictCursor].Path, Status: "UU", Kind: git.ChangeUnmerged}
+				cmd := m.loadFileDiff(conflict)
+				return m, cmd
 			}
 			return m, nil
 		}
@@ -331,7 +726,8 @@ func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
 			m.scrollOffset = 0
 			m.adjustFileScroll()
 			if m.fileCursor < len(m.changes) {
-				return m, m.loadFileDiff(m.changes[m.fileCursor].File)
+				cmd := m.loadFileDiff(m.changes[m.fileCursor])
+				return m, cmd
 			}
 		}
 		return m, nil
@@ -342,14 +738,15 @@ func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
 			m.scrollOffset = 0
 			m.adjustFileScroll()
 			if m.fileCursor < len(m.changes) {
-				return m, m.loadFileDiff(m.changes[m.fileCursor].File)
+				cmd := m.loadFileDiff(m.changes[m.fileCursor])
+				return m, cmd
 			}
 		}
 		return m, nil
 
 	case " ", "space":
 		if m.fileCursor < len(m.changes) {
-			return m, m.toggleStaging(m.changes[m.fileCursor].File)
+			return m, m.toggleStaging(m.changes[m.fileCursor])
 		}
 		return m, nil
 
@@ -369,11 +766,19 @@ func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
 		if m.fileCursor < len(m.changes) {
 			file := m.changes[m.fileCursor].File
 			m.blameFile = file
+			m.blameStack = nil
 			m.viewMode = "blame"
 			return m, m.loadBlame(file)
 		}
 		return m, nil
 
+	case "h":
+		// History of selected file
+		if m.fileCursor < len(m.changes) {
+			return m, m.openFileHistory(m.changes[m.fileCursor].File)
+		}
+		return m, nil
+
 	case "d":
 		if m.fileCursor < len(m.changes) {
 			if m.confirmAction == "" {
@@ -382,7 +787,7 @@ func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
 				return m, nil
 			} else if m.confirmAction == "discard" {
 				m.confirmAction = ""
-				return m, m.discardChanges(m.changes[m.fileCursor].File)
+				return m, m.discardChanges(m.changes[m.fileCursor])
 			}
 		}
 		return m, nil
@@ -390,12 +795,47 @@ func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
 	case "esc":
 		m.confirmAction = ""
 		m.statusMessage = ""
+		m.stashMarks = nil
+		return m, nil
+
+	case "m":
+		// Mark files for a path-limited stash
+		if m.fileCursor < len(m.changes) {
+			file := m.changes[m.fileCursor].File
+			if m.stashMarks == nil {
+				m.stashMarks = make(map[string]bool)
+			}
+			if m.stashMarks[file] {
+				delete(m.stashMarks, file)
+			} else {
+				m.stashMarks[file] = true
+			}
+		}
 		return m, nil
 
+	case "z":
+		// Stash the marked files, or the selected one
+		cmd := m.stashMarked()
+		m.stashMarks = nil
+		return m, cmd
+
 	case "p":
 		m.showDiffPreview = !m.showDiffPreview
 		return m, nil
 
+	case "t":
+		// Unified / side-by-side diff
+		m.diffSplit = !m.diffSplit
+		return m, nil
+
+	case "i", "+", "-", "M":
+		return m.toggleDiffOption(key)
+
+	case "y":
+		// Render a diff held back by the size guard
+		m.diffShowLarge = true
+		return m, nil
+
 	case "w":
 		if m.scrollOffset > 0 {
 			m.scrollOffset--
@@ -427,6 +867,100 @@ func (m model) handleWorkspaceKey(key string) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// toggleDiffOption handles the workspace diff toggles: i (ignore
+// whitespace), +/- (context lines) and M (renames, copies, off)
+func (m model) toggleDiffOption(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "i":
+		m.diffOpts.IgnoreWhitespace = !m.diffOpts.IgnoreWhitespace
+	case "+":
+		m.stepDiffContext(1)
+	case "-":
+		m.stepDiffContext(-1)
+	case "M":
+		switch m.diffOpts.Renames {
+		case git.RenamesOn:
+			m.diffOpts.Renames = git.RenamesCopies
+		case git.RenamesCopies:
+			m.diffOpts.Renames = git.RenamesOff
+		default:
+			m.diffOpts.Renames = git.RenamesOn
+		}
+	}
+	m.scrollOffset = 0
+	cmd := m.reloadFileDiff()
+	return m, cmd
+}
+
+// handleCompareKey drives the comparison view: the file list, and the diff
+// of one file once opened
+func (m model) handleCompareKey(key string) (tea.Model, tea.Cmd) {
+	comparison := m.branchComparison
+	if m.compareDiffPath != "" || m.compareConflicts {
+		switch key {
+		case "esc":
+			m.compareDiffPath = ""
+			m.compareDiff = ""
+			m.compareConflicts = false
+		case "t":
+			m.diffSplit = !m.diffSplit
+		case "j", "down":
+			m.scrollOffset++
+		case "k", "up":
+			if m.scrollOffset > 0 {
+				m.scrollOffset--
+			}
+		}
+		return m, nil
+	}
+
+	switch key {
+	case "esc":
+		m.branchComparison = nil
+		return m, nil
+	case "j", "down":
+		if m.compareCursor < len(comparison.Files)-1 {
+			m.compareCursor++
+			m.adjustCompareScroll()
+		}
+		return m, nil
+	case "k", "up":
+		if m.compareCursor > 0 {
+			m.compareCursor--
+			m.adjustCompareScroll()
+		}
+		return m, nil
+	case "enter", "d":
+		if m.compareCursor < len(comparison.Files) {
+			return m, m.loadCompareDiff(comparison.Files[m.compareCursor])
+		}
+		return m, nil
+	case "x":
+		if len(comparison.Merge.Conflicts) > 0 {
+			m.compareConflicts = true
+			m.scrollOffset = 0
+		}
+		return m, nil
+	case ".":
+		// Two-dot / three-dot
+		return m, m.compareRefs(comparison.TargetBranch, comparison.SourceBranch, !comparison.ThreeDot)
+	case "p":
+		// The picks land on HEAD, so the source side must be what's checked out
+		if source := git.ResolveRev(m.repoPath, comparison.SourceBranch); source == "" || source != git.ResolveRev(m.repoPath, "HEAD") {
+			m.statusMessage = fmt.Sprintf("Cherry-pick needs %s checked out: picks are applied to HEAD", comparison.SourceBranch)
+			m.statusExpiry = time.Now().Add(3 * time.Second)
+			return m, nil
+		}
+		if len(comparison.BehindCommits) == 0 {
+			m.statusMessage = fmt.Sprintf("Nothing to pick: %s has no commits missing from %s", comparison.TargetBranch, comparison.SourceBranch)
+			return m, nil
+		}
+		m.openCherryPick(comparison.BehindCommits, "compare")
+		return m, nil
+	}
+	return m, nil
+}
+
 func (m model) handleCommitKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// If viewing commit summary
 	if m.commitSummary != nil {
@@ -492,12 +1026,28 @@ func (m model) handleCommitKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd)
 func (m model) handleBranchesKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// If comparing branches
 	if m.branchComparison != nil {
+		return m.handleCompareKey(key)
+	}
+
+	// If entering refs to compare
+	if m.compareInput.Focused() {
 		switch key {
+		case "enter":
+			spec := strings.TrimSpace(m.compareInput.Value())
+			if spec == "" {
+				return m, nil
+			}
+			m.compareInput.SetValue("")
+			m.compareInput.Blur()
+			return m, m.compareRefs(git.ParseCompareRange(spec))
 		case "esc":
-			m.branchComparison = nil
+			m.compareInput.SetValue("")
+			m.compareInput.Blur()
 			return m, nil
 		}
-		return m, nil
+		var cmd tea.Cmd
+		m.compareInput, cmd = m.compareInput.Update(msg)
+		return m, cmd
 	}
 
 	// If creating new branch
@@ -568,6 +1118,11 @@ func (m model) handleBranchesKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd
 		}
 		return m, nil
 
+	case "C":
+		// Compare any two refs
+		m.compareInput.Focus()
+		return m, textinput.Blink
+
 	case "esc":
 		m.confirmAction = ""
 		m.statusMessage = ""
@@ -594,11 +1149,82 @@ func (m model) handleToolsKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	}
 
-	// Back to menu
-	if key == "esc" {
+	if m.toolMode == "rebase" && m.rebaseEdit.Focused() {
+		return m.handleRebaseEditKey(key, msg)
+	}
+	if m.toolMode == "reflog" && m.reflogInput.Focused() {
+		switch key {
+		case "enter":
+			name := strings.TrimSpace(m.reflogInput.Value())
+			if name == "" || m.reflogCursor >= len(m.reflog) {
+				return m, nil
+			}
+			m.reflogInput.Blur()
+			return m, m.branchFromReflog(name, m.reflog[m.reflogCursor])
+		case "esc":
+			m.reflogInput.Blur()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.reflogInput, cmd = m.reflogInput.Update(msg)
+		return m, cmd
+	}
+	if m.toolMode == "stash" && m.stashInput.Focused() {
+		switch key {
+		case "enter":
+			value := strings.TrimSpace(m.stashInput.Value())
+			m.stashInput.Blur()
+			switch m.stashField {
+			case "message":
+				opts := m.stashOpts
+				opts.Message = value
+				return m, m.stashPush(opts)
+			case "rename":
+				if value != "" && m.stashCursor < len(m.stashes) {
+					return m, m.stashRename(m.stashCursor, value)
+				}
+			case "branch":
+				if value != "" && m.stashCursor < len(m.stashes) {
+					return m, m.stashToBranch(m.stashCursor, value)
+				}
+			}
+			return m, nil
+		case "esc":
+			m.stashInput.Blur()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.stashInput, cmd = m.stashInput.Update(msg)
+		return m, cmd
+	}
+	if m.toolMode == "bisect" && m.bisectInput.Focused() {
+		switch key {
+		case "enter":
+			command := strings.TrimSpace(m.bisectInput.Value())
+			m.bisectInput.Blur()
+			if command == "" {
+				return m, nil
+			}
+			m.statusMessage = "Running bisect: " + command
+			return m, m.startBisectRun(command)
+		case "esc":
+			m.bisectInput.Blur()
+			m.bisectInput.SetValue("")
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.bisectInput, cmd = m.bisectInput.Update(msg)
+		return m, cmd
+	}
+
+	// Back to menu (log inputs, commit detail, log marks, conflict details,
+	// the cherry-pick panel and the hard reset warning handle esc themselves)
+	if key == "esc" && !m.logInputFocused() && !(m.toolMode == "log" && (m.logDetail != nil || len(m.pickMarks) > 0)) &&
+		!(m.toolMode == "rebase" && m.rebaseConflicts) && m.toolMode != "cherrypick" && m.confirmAction != "reset-hard" {
 		if m.toolMode != "menu" {
 			m.toolMode = "menu"
 			m.pushOutput = ""
+			m.confirmAction = ""
 			return m, nil
 		}
 		return m, nil
@@ -612,8 +1238,8 @@ func (m model) handleToolsKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleUndoKey(key)
 	case "rebase":
 		return m.handleRebaseKey(key)
-	case "history":
-		return m.handleHistoryKey(key)
+	case "reflog":
+		return m.handleReflogKey(key)
 	case "remote":
 		return m.handleRemoteKey(key)
 	case "stash":
@@ -630,6 +1256,12 @@ func (m model) handleToolsKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleInitKey(key, msg)
 	case "clean":
 		return m.handleCleanKey(key)
+	case "operation":
+		return m.handleOperationKey(key)
+	case "bisect":
+		return m.handleBisectKey(key)
+	case "cherrypick":
+		return m.handleCherryPickKey(key)
 	}
 
 	return m, nil
@@ -637,7 +1269,7 @@ func (m model) handleToolsKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 func (m model) handleToolsMenuKey(key string) (tea.Model, tea.Cmd) {
 	// Main tools menu (categories)
-	maxCursor := 11 // 12 items: 0-11
+	maxCursor := 12 // 13 items: 0-12
 
 	switch key {
 	case "j", "down":
@@ -660,13 +1292,16 @@ func (m model) handleToolsMenuKey(key string) (tea.Model, tea.Cmd) {
 		m.toolMode = "tags"
 		return m, m.loadTags()
 	case "h":
-		m.toolMode = "history"
-		return m, m.loadCommitHistory()
+		m.toolMode = "reflog"
+		return m, tea.Batch(m.loadReflog(m.reflogRef, reflogPageSize), m.loadBranches())
 	case "u":
 		m.toolMode = "undo"
 		return m, m.loadCommitHistory()
 	case "r":
 		m.toolMode = "rebase"
+		if m.rebaseState != nil {
+			return m, m.loadRebaseState()
+		}
 		m.rebaseInput.Focus()
 		return m, textinput.Blink
 	case "p":
@@ -696,7 +1331,7 @@ func (m model) handleToolsMenuKey(key string) (tea.Model, tea.Cmd) {
 		return m, nil
 	case "o":
 		m.toolMode = "log"
-		return m, m.loadLogCommits("")
+		return m, m.loadLogCommits()
 	case "c":
 		m.toolMode = "clone"
 		m.cloneInput.Focus()
@@ -708,6 +1343,9 @@ func (m model) handleToolsMenuKey(key string) (tea.Model, tea.Cmd) {
 	case "x":
 		m.toolMode = "clean"
 		return m, m.loadCleanFiles()
+	case "b":
+		m.toolMode = "bisect"
+		return m, m.loadBisectState()
 	}
 	return m, nil
 }
@@ -716,21 +1354,24 @@ func (m model) selectToolMenuItem() (tea.Model, tea.Cmd) {
 	switch m.toolCursor {
 	case 0: // Log
 		m.toolMode = "log"
-		return m, m.loadLogCommits("")
+		return m, m.loadLogCommits()
 	case 1: // Stash
 		m.toolMode = "stash"
 		return m, m.loadStashList()
 	case 2: // Tags
 		m.toolMode = "tags"
 		return m, m.loadTags()
-	case 3: // History
-		m.toolMode = "history"
-		return m, m.loadCommitHistory()
+	case 3: // Reflog
+		m.toolMode = "reflog"
+		return m, tea.Batch(m.loadReflog(m.reflogRef, reflogPageSize), m.loadBranches())
 	case 4: // Undo
 		m.toolMode = "undo"
 		return m, m.loadCommitHistory()
 	case 5: // Rebase
 		m.toolMode = "rebase"
+		if m.rebaseState != nil {
+			return m, m.loadRebaseState()
+		}
 		m.rebaseInput.Focus()
 		return m, textinput.Blink
 	case 6: // Push
@@ -760,79 +1401,196 @@ func (m model) selectToolMenuItem() (tea.Model, tea.Cmd) {
 		m.toolMode = "init"
 		m.initInput.Focus()
 		return m, textinput.Blink
+	case 12: // Bisect
+		m.toolMode = "bisect"
+		return m, m.loadBisectState()
 	}
 	return m, nil
 }
 
 func (m model) handleUndoKey(key string) (tea.Model, tea.Cmd) {
+	// Hard reset over uncommitted changes: y discards them, any other key backs out
+	if m.confirmAction == "reset-hard" {
+		m.confirmAction = ""
+		if key == "y" && m.undoCursor < len(m.commits) {
+			return m, m.undoToCommit(m.commits[m.undoCursor].Hash, git.ResetHard)
+		}
+		m.statusMessage = "Hard reset cancelled"
+		return m, nil
+	}
+
 	switch key {
 	case "j", "down":
+		m.confirmAction = ""
 		if m.undoCursor < len(m.commits)-1 {
 			m.undoCursor++
 			m.adjustUndoScroll()
+			return m, tea.Batch(m.loadResetPreview(), m.loadMoreCommits("history", m.undoCursor))
 		}
-		return m, nil
+		return m, m.loadMoreCommits("history", m.undoCursor)
 	case "k", "up":
+		m.confirmAction = ""
 		if m.undoCursor > 0 {
 			m.undoCursor--
 			m.adjustUndoScroll()
+			return m, m.loadResetPreview()
 		}
 		return m, nil
+	case "m":
+		// soft -> mixed -> hard -> keep
+		i := slices.Index(git.ResetModes, m.resetMode)
+		m.resetMode = git.ResetModes[(i+1)%len(git.ResetModes)]
+		m.confirmAction = ""
+		return m, nil
 	case "enter":
-		if m.undoCursor < len(m.commits) {
-			if m.confirmAction == "" {
-				m.confirmAction = "undo"
-				m.statusMessage = fmt.Sprintf("Press enter again to reset to %s (soft reset, changes kept)", m.commits[m.undoCursor].Hash)
-				return m, nil
-			} else if m.confirmAction == "undo" {
-				m.confirmAction = ""
-				return m, m.undoToCommit(m.commits[m.undoCursor].Hash)
-			}
+		if m.undoCursor >= len(m.commits) || m.resetPreview == nil {
+			return m, nil
 		}
-		return m, nil
+		hash := m.commits[m.undoCursor].Hash
+		if blocked := m.resetPreview.Effect(m.resetMode).Blocked; len(blocked) > 0 {
+			m.statusMessage = fmt.Sprintf("Keep reset would overwrite local changes in %d files: commit or stash them, or pick another mode", len(blocked))
+			return m, nil
+		}
+		if m.confirmAction != "undo" {
+			m.confirmAction = "undo"
+			m.statusMessage = fmt.Sprintf("Press enter again to %s reset to %s", m.resetMode, hash)
+			return m, nil
+		}
+		// Uncommitted changes are in no commit: list them and ask once more
+		if m.resetMode == git.ResetHard && len(m.resetPreview.Dirty) > 0 {
+			m.confirmAction = "reset-hard"
+			m.statusMessage = fmt.Sprintf("Press y to discard uncommitted changes in %d files", len(m.resetPreview.Dirty))
+			return m, nil
+		}
+		m.confirmAction = ""
+		return m, m.undoToCommit(hash, m.resetMode)
 	}
 	m.confirmAction = ""
 	return m, nil
 }
 
 func (m model) handleRebaseKey(key string) (tea.Model, tea.Cmd) {
+	if m.rebaseState != nil {
+		return m.handleRebasePanelKey(key)
+	}
 	if len(m.rebaseCommits) == 0 {
 		return m, nil
 	}
 
+	// Predicted conflict details
+	if m.rebaseConflicts {
+		switch key {
+		case "esc", "x":
+			m.rebaseConflicts = false
+			m.scrollOffset = 0
+		case "j", "down":
+			m.scrollOffset++
+		case "k", "up":
+			if m.scrollOffset > 0 {
+				m.scrollOffset--
+			}
+		case "enter":
+			m.rebaseConflicts = false
+			return m.handleRebaseKey(key)
+		}
+		return m, nil
+	}
+
+	actions := map[string]string{"p": "pick", "s": "squash", "e": "edit", "d": "drop", "f": "fixup"}
+	if action, ok := actions[key]; ok {
+		if m.rebaseCommits[m.rebaseCursor].Action == "exec" {
+			if action == "drop" {
+				m.rebaseCommits = append(m.rebaseCommits[:m.rebaseCursor], m.rebaseCommits[m.rebaseCursor+1:]...)
+				m.rebaseCursor = min(m.rebaseCursor, len(m.rebaseCommits)-1)
+				m.adjustRebaseScroll()
+				m.rebasePlanChanged()
+			}
+			return m, nil
+		}
+		m.rebaseCommits[m.rebaseCursor].Action = action
+		m.rebasePlanChanged()
+		return m, nil
+	}
+
 	switch key {
 	case "j", "down":
 		if m.rebaseCursor < len(m.rebaseCommits)-1 {
 			m.rebaseCursor++
+			m.adjustRebaseScroll()
 		}
 		return m, nil
 	case "k", "up":
 		if m.rebaseCursor > 0 {
 			m.rebaseCursor--
+			m.adjustRebaseScroll()
 		}
 		return m, nil
-	case "p":
-		m.rebaseCommits[m.rebaseCursor].Action = "pick"
+	case "J", "shift+down":
+		// Down the list is earlier in history
+		if m.rebaseCursor < len(m.rebaseCommits)-1 {
+			c := m.rebaseCommits
+			c[m.rebaseCursor], c[m.rebaseCursor+1] = c[m.rebaseCursor+1], c[m.rebaseCursor]
+			m.rebaseCursor++
+			m.adjustRebaseScroll()
+			m.rebasePlanChanged()
+		}
 		return m, nil
-	case "s":
-		m.rebaseCommits[m.rebaseCursor].Action = "squash"
+	case "K", "shift+up":
+		if m.rebaseCursor > 0 {
+			c := m.rebaseCommits
+			c[m.rebaseCursor], c[m.rebaseCursor-1] = c[m.rebaseCursor-1], c[m.rebaseCursor]
+			m.rebaseCursor--
+			m.adjustRebaseScroll()
+			m.rebasePlanChanged()
+		}
 		return m, nil
 	case "r":
-		m.rebaseCommits[m.rebaseCursor].Action = "reword"
+		commit := m.rebaseCommits[m.rebaseCursor]
+		if commit.Action == "exec" {
+			return m, nil
+		}
+		value := commit.Message
+		if commit.NewMessage != "" {
+			value = commit.NewMessage
+		}
+		return m, m.editRebaseField("reword", value, "New commit message")
+	case "!":
+		return m, m.editRebaseField("exec", "", "Command to run after the selected commit (e.g. make test)")
+	case "o":
+		return m, m.editRebaseField("onto", m.rebaseOpts.Onto, "Branch or ref to rebase onto (empty: rebase in place)")
+	case "a":
+		m.rebaseOpts.Autosquash = !m.rebaseOpts.Autosquash
+		if m.rebaseOpts.Autosquash {
+			m.rebaseUnsquashed = append([]git.RebaseCommit(nil), m.rebaseCommits...)
+			m.rebaseCommits = git.AutosquashPlan(m.rebaseCommits)
+			m.statusMessage = "Autosquash on: fixup!/squash! commits moved after their targets"
+		} else if m.rebaseUnsquashed != nil {
+			m.rebaseCommits = git.UndoAutosquash(m.rebaseCommits, m.rebaseUnsquashed)
+			m.rebaseUnsquashed = nil
+			m.statusMessage = "Autosquash off: fixup!/squash! commits moved back, other edits kept"
+		}
+		m.rebaseCursor = min(m.rebaseCursor, len(m.rebaseCommits)-1)
+		m.adjustRebaseScroll()
+		m.rebasePlanChanged()
 		return m, nil
-	case "d":
-		m.rebaseCommits[m.rebaseCursor].Action = "drop"
+	case "u":
+		m.rebaseOpts.UpdateRefs = !m.rebaseOpts.UpdateRefs
+		m.rebasePlanChanged()
 		return m, nil
-	case "f":
-		m.rebaseCommits[m.rebaseCursor].Action = "fixup"
+	case "x":
+		if m.rebasePrediction != nil && len(m.rebasePrediction.Conflicts) > 0 {
+			m.rebaseConflicts = true
+			m.scrollOffset = 0
+		}
 		return m, nil
 	case "enter":
 		if m.confirmAction == "" {
-			m.confirmAction = "rebase"
-			m.statusMessage = "Press enter again to execute rebase (rewrites history!)"
-			return m, nil
+			// Dry run first; the result asks for the second enter
+			m.statusMessage = "Checking for conflicts..."
+			return m, m.predictRebase()
 		} else if m.confirmAction == "rebase" {
 			m.confirmAction = ""
+			m.rebasePrediction = nil
 			return m, m.executeRebase()
 		}
 		return m, nil
@@ -841,24 +1599,84 @@ func (m model) handleRebaseKey(key string) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-func (m model) handleHistoryKey(key string) (tea.Model, tea.Cmd) {
+func (m model) handleReflogKey(key string) (tea.Model, tea.Cmd) {
+	if m.reflogCursor >= len(m.reflog) {
+		if key == "b" || key == "r" {
+			return m, m.loadReflog(m.nextReflogRef(key), reflogPageSize)
+		}
+		return m, nil
+	}
+	entry := m.reflog[m.reflogCursor]
+
 	switch key {
 	case "j", "down":
-		if m.historyCursor < len(m.commits)-1 {
-			m.historyCursor++
-			m.adjustHistoryScroll()
+		if m.reflogCursor < len(m.reflog)-1 {
+			m.reflogCursor++
+			m.adjustReflogScroll()
+		}
+		// A full page means there may be older entries
+		if m.reflogCursor == len(m.reflog)-1 && len(m.reflog)%reflogPageSize == 0 {
+			return m, m.loadReflog(m.reflogRef, len(m.reflog)+reflogPageSize)
 		}
 		return m, nil
 	case "k", "up":
-		if m.historyCursor > 0 {
-			m.historyCursor--
-			m.adjustHistoryScroll()
+		if m.reflogCursor > 0 {
+			m.reflogCursor--
+			m.adjustReflogScroll()
+		}
+		return m, nil
+	case "enter":
+		m.confirmAction = ""
+		m.toolMode = "log"
+		m.logDetailReturn = "reflog"
+		m.scrollOffset = 0
+		return m, m.loadLogDetail(entry.Hash)
+	case "n":
+		m.confirmAction = ""
+		m.reflogInput.SetValue("")
+		m.reflogInput.Focus()
+		return m, textinput.Blink
+	case "R":
+		if m.confirmAction != "reflog-reset" {
+			m.confirmAction = "reflog-reset"
+			m.statusMessage = fmt.Sprintf("Press R again to reset %s to %s (%s %s); uncommitted changes are kept",
+				m.gitState.Branch, entry.Selector, entry.Hash, entry.Subject)
+			return m, nil
 		}
+		m.confirmAction = ""
+		return m, m.resetToReflog(entry)
+	case "c":
+		m.confirmAction = ""
+		m.openCherryPick([]git.Commit{{Hash: entry.Hash, Message: entry.Subject, Date: entry.Date, Parents: entry.Parents}}, "reflog")
 		return m, nil
+	case "b", "r":
+		m.confirmAction = ""
+		return m, m.loadReflog(m.nextReflogRef(key), reflogPageSize)
 	}
+	m.confirmAction = ""
 	return m, nil
 }
 
+// nextReflogRef is the ref to show after b (cycle HEAD and the local
+// branches) or r (refresh the current one)
+func (m model) nextReflogRef(key string) string {
+	if key == "r" {
+		return m.reflogRef
+	}
+	refs := []string{"HEAD"}
+	for _, branch := range m.branches {
+		if !branch.IsRemote {
+			refs = append(refs, branch.Name)
+		}
+	}
+	for i, ref := range refs {
+		if ref == m.reflogRef {
+			return refs[(i+1)%len(refs)]
+		}
+	}
+	return "HEAD"
+}
+
 func (m model) handleRemoteKey(key string) (tea.Model, tea.Cmd) {
 	switch key {
 	case "p":
@@ -911,7 +1729,40 @@ func (m model) handleStashKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	case "s":
 		// Create new stash
-		return m, m.stashPush("")
+		return m, m.stashPush(m.stashOpts)
+	case "m":
+		return m.editStash("message", "", "Stash message...")
+	case "r":
+		if m.stashCursor < len(m.stashes) {
+			// Edit the message without the "On main: " prefix
+			current := m.stashes[m.stashCursor].Message
+			if _, rest, ok := strings.Cut(current, ": "); ok {
+				current = rest
+			}
+			return m.editStash("rename", current, "New stash message...")
+		}
+		return m, nil
+	case "b":
+		if m.stashCursor < len(m.stashes) {
+			return m.editStash("branch", "", "Branch name...")
+		}
+		return m, nil
+	case "u":
+		m.stashOpts.IncludeUntracked = !m.stashOpts.IncludeUntracked
+		if m.stashOpts.IncludeUntracked {
+			m.stashOpts.Staged = false
+		}
+		return m, nil
+	case "i":
+		m.stashOpts.KeepIndex = !m.stashOpts.KeepIndex
+		return m, nil
+	case "t":
+		// git refuses --staged with --include-untracked
+		m.stashOpts.Staged = !m.stashOpts.Staged
+		if m.stashOpts.Staged {
+			m.stashOpts.IncludeUntracked = false
+		}
+		return m, nil
 	case "p", "enter":
 		// Pop stash (removes from stash list)
 		if m.stashCursor < len(m.stashes) {
@@ -948,6 +1799,17 @@ func (m model) handleStashKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// editStash focuses the stash input for field
+func (m model) editStash(field, value, placeholder string) (tea.Model, tea.Cmd) {
+	m.stashField = field
+	m.stashInput.Placeholder = placeholder
+	m.stashInput.SetValue(value)
+	m.stashInput.CursorEnd()
+	m.stashInput.Focus()
+	m.confirmAction = ""
+	return m, textinput.Blink
+}
+
 func (m model) handleTagsKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// If creating new tag
 	if m.tagInput.Focused() {
@@ -1052,6 +1914,18 @@ func (m model) handleHooksKey(key string) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// textInputFocused reports whether keys are going into a text field
+func (m model) textInputFocused() bool {
+	return (m.tab == "commit" && m.commitInput.Focused()) || m.branchInput.Focused() || m.compareInput.Focused() ||
+		m.tagInput.Focused() || m.cloneInput.Focused() || m.initInput.Focused() ||
+		m.logInputFocused() || m.rebaseInputFocused() || m.bisectInput.Focused() || m.reflogInput.Focused() || m.stashInput.Focused()
+}
+
+// logInputFocused reports whether the log search or filter bar has focus
+func (m model) logInputFocused() bool {
+	return m.toolMode == "log" && (m.logSearchInput.Focused() || m.logFilterInput.Focused())
+}
+
 func (m model) handleLogKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// If viewing commit detail
 	if m.logDetail != nil {
@@ -1059,6 +1933,23 @@ func (m model) handleLogKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		case "esc":
 			m.logDetail = nil
 			m.logDiff = ""
+			switch m.logDetailReturn {
+			case "blame":
+				m.tab = "workspace"
+			case "bisect":
+				m.toolMode = "bisect"
+			case "reflog":
+				m.toolMode = "reflog"
+			}
+			m.logDetailReturn = ""
+			return m, nil
+		case "t":
+			m.diffSplit = !m.diffSplit
+			return m, nil
+		case "i", "+", "-", "M":
+			return m.toggleDiffOption(key)
+		case "y":
+			m.diffShowLarge = true
 			return m, nil
 		case "j", "down":
 			m.scrollOffset++
@@ -1079,7 +1970,9 @@ func (m model) handleLogKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			search := strings.TrimSpace(m.logSearchInput.Value())
 			m.logSearchInput.Blur()
 			m.logSearch = search
-			return m, m.loadLogCommits(search)
+			m.logCursor = 0
+			m.logOffset = 0
+			return m, m.loadLogCommits()
 		case "esc":
 			m.logSearchInput.Blur()
 			return m, nil
@@ -1089,13 +1982,42 @@ func (m model) handleLogKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, cmd
 	}
 
+	// If editing the filter bar or naming a preset
+	if m.logFilterInput.Focused() {
+		switch key {
+		case "enter":
+			value := strings.TrimSpace(m.logFilterInput.Value())
+			if m.logSavePreset {
+				if value == "" {
+					return m, nil
+				}
+				m.logSavePreset = false
+				m.logFilterInput.Blur()
+				return m, m.saveLogPreset(value)
+			}
+			if _, err := git.ParseLogFilter(value); err != nil {
+				m.statusMessage = fmt.Sprintf("Invalid filter: %v", err)
+				return m, nil
+			}
+			m.logFilterInput.Blur()
+			return m, m.applyLogFilter(value, "")
+		case "esc":
+			m.logSavePreset = false
+			m.logFilterInput.Blur()
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.logFilterInput, cmd = m.logFilterInput.Update(msg)
+		return m, cmd
+	}
+
 	switch key {
 	case "j", "down":
 		if m.logCursor < len(m.logCommits)-1 {
 			m.logCursor++
 			m.adjustLogScroll()
 		}
-		return m, nil
+		return m, m.loadMoreCommits("log", m.logCursor)
 	case "k", "up":
 		if m.logCursor > 0 {
 			m.logCursor--
@@ -1110,10 +2032,111 @@ func (m model) handleLogKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "/":
 		m.logSearchInput.Focus()
 		return m, textinput.Blink
-	case "c":
-		// Cherry-pick selected commit
+	case "f":
+		m.logFilterInput.SetValue(m.logFilter.String())
+		m.logFilterInput.CursorEnd()
+		m.logFilterInput.Focus()
+		return m, textinput.Blink
+	case "F":
+		// Clear search and filters
+		m.logSearch = ""
+		m.logSearchInput.SetValue("")
+		return m, m.applyLogFilter("", "")
+	case "p":
+		// Cycle through saved presets, then back to unfiltered
+		presets := m.config.LogPresets
+		if len(presets) == 0 {
+			m.statusMessage = "No saved presets (press P to save the current filter)"
+			return m, nil
+		}
+		next := 0
+		for i, p := range presets {
+			if p.Name == m.logPreset {
+				next = i + 1
+			}
+		}
+		if next == len(presets) {
+			return m, m.applyLogFilter("", "")
+		}
+		return m, m.applyLogFilter(presets[next].Filter, presets[next].Name)
+	case "P":
+		if m.logFilter.String() == "" {
+			m.statusMessage = "No filter to save (press f to set one)"
+			return m, nil
+		}
+		m.logSavePreset = true
+		m.logFilterInput.SetValue(m.logPreset)
+		m.logFilterInput.CursorEnd()
+		m.logFilterInput.Focus()
+		return m, textinput.Blink
+	case "b", "g":
+		// Pick the ends of a bisect, or mark commits in a running one
+		if m.logCursor >= len(m.logCommits) {
+			return m, nil
+		}
+		hash := m.logCommits[m.logCursor].Hash
+		mark := map[string]string{"b": "bad", "g": "good"}[key]
+		if m.operation.Op == git.OpBisect {
+			return m, m.markBisect(mark, hash)
+		}
+		if key == "b" {
+			m.bisectBad = hash
+		} else {
+			m.bisectGood = hash
+		}
+		switch {
+		case m.bisectGood == "":
+			m.statusMessage = fmt.Sprintf("Bisect: %s is bad, press g on a commit known to be good", hash)
+			return m, nil
+		case m.bisectBad == "":
+			m.statusMessage = fmt.Sprintf("Bisect: %s is good, press b on a commit known to be bad", hash)
+			return m, nil
+		}
+		bad, good := m.bisectBad, m.bisectGood
+		m.bisectBad, m.bisectGood = "", ""
+		m.toolMode = "bisect"
+		return m, m.startBisect(bad, good)
+	case " ", "space":
+		// Mark the commit for a cherry-pick
 		if m.logCursor < len(m.logCommits) {
-			return m, m.cherryPickCommit(m.logCommits[m.logCursor].Hash)
+			hash := m.logCommits[m.logCursor].Hash
+			if m.pickMarks == nil {
+				m.pickMarks = make(map[string]bool)
+			}
+			if m.pickMarks[hash] {
+				delete(m.pickMarks, hash)
+			} else {
+				m.pickMarks[hash] = true
+			}
+			m.pickAnchor = m.logCursor
+		}
+		return m, nil
+	case "v":
+		// Mark every commit from the last marked one to the cursor
+		if m.logCursor < len(m.logCommits) && len(m.pickMarks) > 0 {
+			from, to := min(m.pickAnchor, m.logCursor), max(m.pickAnchor, m.logCursor)
+			for i := from; i <= to && i < len(m.logCommits); i++ {
+				m.pickMarks[m.logCommits[i].Hash] = true
+			}
+			m.pickAnchor = m.logCursor
+		}
+		return m, nil
+	case "esc":
+		m.pickMarks = nil
+		return m, nil
+	case "c":
+		// Cherry-pick the marked commits, or the selected one
+		var commits []git.Commit
+		for _, commit := range m.logCommits {
+			if m.pickMarks[commit.Hash] {
+				commits = append(commits, commit)
+			}
+		}
+		if len(commits) == 0 && m.logCursor < len(m.logCommits) {
+			commits = append(commits, m.logCommits[m.logCursor])
+		}
+		if len(commits) > 0 {
+			m.openCherryPick(commits, "log")
 		}
 		return m, nil
 	case "R":
@@ -1245,8 +2268,36 @@ func (m *model) adjustBranchScroll() {
 	}
 }
 
+func (m *model) adjustCompareScroll() {
+	visibleItems := m.height - uiOverhead - len(m.compareHeader(m.width)) - 2
+	if visibleItems < 1 {
+		visibleItems = 1
+	}
+
+	if m.compareCursor < m.compareOffset {
+		m.compareOffset = m.compareCursor
+	}
+	if m.compareCursor >= m.compareOffset+visibleItems {
+		m.compareOffset = m.compareCursor - visibleItems + 1
+	}
+}
+
+func (m *model) adjustPickScroll() {
+	visibleItems := m.height - uiOverhead - 5
+	if visibleItems < 1 {
+		visibleItems = 1
+	}
+
+	if m.pickCursor < m.pickOffset {
+		m.pickOffset = m.pickCursor
+	}
+	if m.pickCursor >= m.pickOffset+visibleItems {
+		m.pickOffset = m.pickCursor - visibleItems + 1
+	}
+}
+
 func (m *model) adjustUndoScroll() {
-	visibleItems := m.height - uiOverhead - 4
+	visibleItems := undoListHeight(m.height-uiOverhead) - 2
 	if visibleItems < 1 {
 		visibleItems = 1
 	}
@@ -1259,22 +2310,329 @@ func (m *model) adjustUndoScroll() {
 	}
 }
 
-func (m *model) adjustHistoryScroll() {
-	visibleItems := m.height - uiOverhead - 4
+func (m *model) adjustReflogScroll() {
+	visibleItems := m.height - uiOverhead - 5
+	if visibleItems < 1 {
+		visibleItems = 1
+	}
+
+	if m.reflogCursor < m.reflogOffset {
+		m.reflogOffset = m.reflogCursor
+	}
+	if m.reflogCursor >= m.reflogOffset+visibleItems {
+		m.reflogOffset = m.reflogCursor - visibleItems + 1
+	}
+}
+
+// handleRebasePanelKey drives a rebase that stopped on a conflict, edit or
+// failed exec
+func (m model) handleRebasePanelKey(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "c":
+		m.confirmAction = ""
+		m.statusMessage = "Continuing rebase..."
+		return m, m.controlOperation(git.OpRebase, "continue")
+	case "s":
+		if m.confirmAction != "rebase-skip" {
+			m.confirmAction = "rebase-skip"
+			m.statusMessage = "Press s again to skip this commit (its changes are dropped)"
+			return m, nil
+		}
+		m.confirmAction = ""
+		return m, m.controlOperation(git.OpRebase, "skip")
+	case "a":
+		if m.confirmAction != "rebase-abort" {
+			m.confirmAction = "rebase-abort"
+			m.statusMessage = "Press a again to abort the rebase and restore the branch"
+			return m, nil
+		}
+		m.confirmAction = ""
+		return m, m.controlOperation(git.OpRebase, "abort")
+	case "e":
+		if m.rebaseState.Interactive {
+			m.confirmAction = ""
+			return m, m.editRebaseTodo()
+		}
+	case "x":
+		if len(m.rebaseState.Conflicts) > 0 {
+			m.confirmAction = ""
+			m.tab = "workspace"
+			m.viewMode = "conflicts"
+			return m, m.loadConflicts()
+		}
+	case "r":
+		return m, m.loadRebaseState()
+	}
+	m.confirmAction = ""
+	return m, nil
+}
+
+// handleOperationKey drives a merge, cherry-pick or revert that is waiting
+// on the user
+func (m model) handleOperationKey(key string) (tea.Model, tea.Cmd) {
+	op := m.operation.Op
+	switch key {
+	case "c":
+		m.confirmAction = ""
+		return m, m.controlOperation(op, "continue")
+	case "s":
+		if op == git.OpCherryPick || op == git.OpRevert {
+			if m.confirmAction != "operation-skip" {
+				m.confirmAction = "operation-skip"
+				m.statusMessage = "Press s again to skip this commit"
+				return m, nil
+			}
+			m.confirmAction = ""
+			return m, m.controlOperation(op, "skip")
+		}
+	case "a":
+		if m.confirmAction != "operation-abort" {
+			m.confirmAction = "operation-abort"
+			m.statusMessage = fmt.Sprintf("Press a again to abort the %s and restore the previous state", strings.ToLower(operationNames[op]))
+			return m, nil
+		}
+		m.confirmAction = ""
+		return m, m.controlOperation(op, "abort")
+	case "x":
+		if len(m.operation.Conflicts) > 0 {
+			m.confirmAction = ""
+			m.tab = "workspace"
+			m.viewMode = "conflicts"
+			return m, m.loadConflicts()
+		}
+	case "r":
+		return m, m.loadOperation()
+	}
+	m.confirmAction = ""
+	return m, nil
+}
+
+func (m model) handleBisectKey(key string) (tea.Model, tea.Cmd) {
+	if m.bisectRun != nil {
+		if key == "x" {
+			m.bisectRun.Stop()
+			m.statusMessage = "Stopping bisect run..."
+		}
+		return m, nil
+	}
+	if m.bisectState == nil {
+		if key == "o" {
+			m.toolMode = "log"
+			return m, m.loadLogCommits()
+		}
+		return m, nil
+	}
+
+	switch key {
+	case "g", "b", "s":
+		m.confirmAction = ""
+		return m, m.markBisect(map[string]string{"g": "good", "b": "bad", "s": "skip"}[key])
+	case "r":
+		m.confirmAction = ""
+		m.bisectInput.Focus()
+		return m, textinput.Blink
+	case "enter":
+		if m.bisectState.FirstBad != nil {
+			return m, m.showFirstBadCommit()
+		}
+		return m, nil
+	case "a":
+		if m.confirmAction != "bisect-reset" {
+			m.confirmAction = "bisect-reset"
+			m.statusMessage = "Press a again to end the bisect and return to the original branch"
+			return m, nil
+		}
+		m.confirmAction = ""
+		return m, m.resetBisect()
+	}
+	m.confirmAction = ""
+	return m, nil
+}
+
+// openCherryPick queues commits listed newest first, as in the log and the
+// comparison, and opens the cherry-pick panel with all of them selected
+func (m *model) openCherryPick(commits []git.Commit, from string) {
+	n := len(commits)
+	m.pickCommits = make([]git.Commit, n)
+	m.pickSelected = make([]bool, n)
+	for i, commit := range commits {
+		m.pickCommits[n-1-i] = commit
+		m.pickSelected[n-1-i] = true
+	}
+	m.pickCursor = 0
+	m.pickOffset = 0
+	m.pickOpts = git.CherryPickOptions{}
+	if m.pickMergeParents() > 0 {
+		m.pickOpts.Mainline = 1
+	}
+	m.pickReturn = from
+	m.confirmAction = ""
+	m.tab = "tools"
+	m.toolMode = "cherrypick"
+}
+
+// leaveCherryPick returns to the log or comparison the panel was opened
+// from, reloading it since a pick may have added commits
+func (m *model) leaveCherryPick() tea.Cmd {
+	m.pickCommits, m.pickSelected = nil, nil
+	if m.pickReturn == "compare" && m.branchComparison != nil {
+		c := m.branchComparison
+		m.tab = "branches"
+		m.toolMode = "menu"
+		return m.compareRefs(c.TargetBranch, c.SourceBranch, c.ThreeDot)
+	}
+	if m.pickReturn == "reflog" {
+		m.toolMode = "reflog"
+		return m.loadReflog(m.reflogRef, max(len(m.reflog), reflogPageSize))
+	}
+	m.toolMode = "log"
+	return m.loadLogCommits()
+}
+
+func (m model) handleCherryPickKey(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "j", "down":
+		if m.pickCursor < len(m.pickCommits)-1 {
+			m.pickCursor++
+			m.adjustPickScroll()
+		}
+	case "k", "up":
+		if m.pickCursor > 0 {
+			m.pickCursor--
+			m.adjustPickScroll()
+		}
+	case " ", "space":
+		if m.pickCursor < len(m.pickSelected) {
+			m.pickSelected[m.pickCursor] = !m.pickSelected[m.pickCursor]
+		}
+	case "a":
+		// Select all, or none when all are selected
+		all := !slices.Contains(m.pickSelected, false)
+		for i := range m.pickSelected {
+			m.pickSelected[i] = !all
+		}
+	case "x":
+		m.pickOpts.RecordOrigin = !m.pickOpts.RecordOrigin
+	case "n":
+		m.pickOpts.NoCommit = !m.pickOpts.NoCommit
+	case "m":
+		// Cycle the parent merge commits are diffed against
+		if parents := m.pickMergeParents(); parents > 0 {
+			m.pickOpts.Mainline = m.pickOpts.Mainline%parents + 1
+		}
+	case "enter":
+		if !slices.Contains(m.pickSelected, true) {
+			m.statusMessage = "No commits selected"
+			return m, nil
+		}
+		// git applies -m to every commit, and only merges have a parent 2
+		if m.pickOpts.Mainline > 1 && m.pickMergeParents() > 0 {
+			for i, commit := range m.pickCommits {
+				if m.pickSelected[i] && len(commit.Parents) < m.pickOpts.Mainline {
+					m.statusMessage = fmt.Sprintf("%s has no parent %d: pick it separately or use mainline 1", commit.Hash, m.pickOpts.Mainline)
+					return m, nil
+				}
+			}
+		}
+		m.statusMessage = "Cherry-picking..."
+		return m, m.cherryPickCommits()
+	case "esc":
+		return m, m.leaveCherryPick()
+	}
+	return m, nil
+}
+
+// showFirstBadCommit opens the bisect result in the log detail view
+func (m *model) showFirstBadCommit() tea.Cmd {
+	commit := m.bisectState.FirstBad
+	m.toolMode = "log"
+	m.logDetailReturn = "bisect"
+	m.scrollOffset = 0
+	m.statusMessage = fmt.Sprintf("First bad commit: %s %s (esc: back to the bisect)", commit.Hash, commit.Message)
+	return m.loadLogDetail(commit.Hash)
+}
+
+func (m model) rebaseInputFocused() bool {
+	return m.toolMode == "rebase" && (m.rebaseInput.Focused() || m.rebaseEdit.Focused())
+}
+
+// rebasePlanChanged drops the prediction and confirmation of the old plan
+func (m *model) rebasePlanChanged() {
+	m.rebasePrediction = nil
+	m.confirmAction = ""
+}
+
+// editRebaseField opens the inline input for a reword message, exec
+// command or onto ref
+func (m *model) editRebaseField(field, value, placeholder string) tea.Cmd {
+	m.rebaseField = field
+	m.rebaseEdit.Placeholder = placeholder
+	m.rebaseEdit.SetValue(value)
+	m.rebaseEdit.CursorEnd()
+	m.rebaseEdit.Focus()
+	return textinput.Blink
+}
+
+func (m model) handleRebaseEditKey(key string, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch key {
+	case "esc":
+		m.rebaseEdit.Blur()
+		return m, nil
+	case "enter":
+		value := strings.TrimSpace(m.rebaseEdit.Value())
+		switch m.rebaseField {
+		case "reword":
+			if value == "" {
+				m.statusMessage = "Commit message cannot be empty"
+				return m, nil
+			}
+			commit := &m.rebaseCommits[m.rebaseCursor]
+			commit.Action = "reword"
+			commit.NewMessage = ""
+			if value != commit.Message {
+				commit.NewMessage = value
+			}
+		case "exec":
+			if value == "" {
+				m.rebaseEdit.Blur()
+				return m, nil
+			}
+			// Above the cursor in the newest-first list runs right after it
+			line := git.RebaseCommit{Action: "exec", Command: value}
+			m.rebaseCommits = append(m.rebaseCommits[:m.rebaseCursor], append([]git.RebaseCommit{line}, m.rebaseCommits[m.rebaseCursor:]...)...)
+			m.adjustRebaseScroll()
+		case "onto":
+			m.rebaseOpts.Onto = value
+		}
+		m.rebaseEdit.Blur()
+		m.rebasePlanChanged()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.rebaseEdit, cmd = m.rebaseEdit.Update(msg)
+	return m, cmd
+}
+
+func (m *model) adjustRebaseScroll() {
+	visibleItems := m.height - uiOverhead - 6
+	if m.rebasePrediction != nil {
+		visibleItems-- // prediction line under the plan
+	}
+	visibleItems -= 2 // options line and second help line
 	if visibleItems < 1 {
 		visibleItems = 1
 	}
 
-	if m.historyCursor < m.historyOffset {
-		m.historyOffset = m.historyCursor
+	if m.rebaseCursor < m.rebaseOffset {
+		m.rebaseOffset = m.rebaseCursor
 	}
-	if m.historyCursor >= m.historyOffset+visibleItems {
-		m.historyOffset = m.historyCursor - visibleItems + 1
+	if m.rebaseCursor >= m.rebaseOffset+visibleItems {
+		m.rebaseOffset = m.rebaseCursor - visibleItems + 1
 	}
 }
 
 func (m *model) adjustStashScroll() {
-	visibleItems := m.height - uiOverhead - 4
+	visibleItems := m.height - uiOverhead - 6
 	if visibleItems < 1 {
 		visibleItems = 1
 	}
@@ -1315,6 +2673,20 @@ func (m *model) adjustLogScroll() {
 	}
 }
 
+func (m *model) adjustFileLogScroll() {
+	visibleItems := m.height - uiOverhead - 4
+	if visibleItems < 1 {
+		visibleItems = 1
+	}
+
+	if m.fileLogCursor < m.fileLogOffset {
+		m.fileLogOffset = m.fileLogCursor
+	}
+	if m.fileLogCursor >= m.fileLogOffset+visibleItems {
+		m.fileLogOffset = m.fileLogCursor - visibleItems + 1
+	}
+}
+
 func (m *model) adjustBlameScroll() {
 	visibleItems := m.height - uiOverhead - 4
 	if visibleItems < 1 {
@@ -6,6 +6,9 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/LFroesch/gitty/internal/git"
 )
 
 // View is the main render function
@@ -33,8 +36,12 @@ func (m model) renderTopBar() string {
 	// Git status info
 	statusInfo := m.renderGitStatusInfo()
 
-	// Tabs
+	// Tabs, with the in-progress operation banner on the right
 	tabs := m.renderTabs()
+	if banner := m.renderOperationBanner(m.width - 4 - lipgloss.Width(tabs)); banner != "" {
+		gap := m.width - 2 - lipgloss.Width(tabs) - lipgloss.Width(banner)
+		tabs += lipgloss.NewStyle().Background(lipgloss.Color("236")).Render(strings.Repeat(" ", max(1, gap))) + banner
+	}
 
 	spacer := lipgloss.NewStyle().Background(lipgloss.Color("236")).Render("  ")
 	leftPart := lipgloss.JoinHorizontal(lipgloss.Top, title, repoName, spacer, statusInfo)
@@ -72,6 +79,51 @@ func (m model) renderGitStatusInfo() string {
 	return strings.Join(parts, styledSpace)
 }
 
+// renderOperationBanner names the merge, rebase, cherry-pick, revert or
+// bisect in progress, shortened to fit width; empty when idle
+func (m model) renderOperationBanner(width int) string {
+	op := m.operation
+	if op.Op == git.OpNone {
+		return ""
+	}
+
+	label := strings.ToUpper(operationNames[op.Op]) + " IN PROGRESS"
+	var details []string
+	switch {
+	case op.Op == git.OpRebase && m.rebaseState != nil:
+		details = append(details, fmt.Sprintf("%d/%d", m.rebaseState.Step, m.rebaseState.Total))
+	case op.Op == git.OpMerge && op.Subject != "":
+		details = append(details, op.Subject)
+	case op.Head != "":
+		details = append(details, op.Head)
+	}
+	if n := len(op.Conflicts); n > 0 {
+		details = append(details, fmt.Sprintf("%d conflicts", n))
+	}
+	hint := "O: actions"
+
+	// Drop details, the hint, then "in progress" until it fits
+	for {
+		parts := append([]string{"⚠ " + label}, details...)
+		if hint != "" {
+			parts = append(parts, hint)
+		}
+		text := strings.Join(parts, " · ")
+		switch {
+		case lipgloss.Width(text)+2 <= width:
+			return operationBannerStyle.Render(text)
+		case len(details) > 0:
+			details = details[:len(details)-1]
+		case hint != "":
+			hint = ""
+		case strings.HasSuffix(label, " IN PROGRESS"):
+			label = strings.TrimSuffix(label, " IN PROGRESS")
+		default:
+			return ""
+		}
+	}
+}
+
 func (m model) renderTabs() string {
 	tab1 := m.renderTab("1", "Workspace", m.tab == "workspace")
 	tab2 := m.renderTab("2", "Commit", m.tab == "commit")
@@ -127,12 +179,24 @@ func (m model) renderStatusBar() string {
 
 	switch m.tab {
 	case "workspace":
-		if m.viewMode == "diff" || m.viewMode == "blame" || m.viewMode == "conflicts" {
+		if m.viewMode == "filelog" {
+			helpText = k("j/k") + d(": nav") + sep + k("enter/d") + d(": diff") + sep +
+				k("o") + d(": file at revision") + sep + k("esc") + d(": back")
+		} else if m.viewMode == "blame" {
+			helpText = k("esc") + d(": back") + sep + k("j/k") + d(": scroll") + sep + k("h") + d(": file history")
+		} else if m.viewMode == "diff" {
+			helpText = k("esc") + d(": back") + sep + k("j/k") + d(": scroll") + sep + k("t") + d(": split/unified") + sep +
+				k("i") + d(": whitespace") + sep + k("+/-") + d(": context") + sep + k("M") + d(": renames")
+			if label := diffOptsLabel(m.diffOpts); label != "" {
+				helpText = helpStyle.Render(label+" ") + helpText
+			}
+		} else if m.viewMode == "conflicts" {
 			helpText = k("esc") + d(": back") + sep + k("j/k") + d(": scroll")
 		} else {
 			helpText = k("j/k") + d(": nav") + sep + k("space") + d(": stage") + sep +
 				k("a") + d(": all") + sep + k("R") + d(": reset commit") + sep +
-				k("enter") + d(": diff") + sep + k("b") + d(": blame") + sep + k("d") + d(": discard")
+				k("enter") + d(": diff") + sep + k("t") + d(": split") + sep + k("b") + d(": blame") + sep +
+				k("h") + d(": history") + sep + k("d") + d(": discard") + sep + k("m/z") + d(": mark/stash")
 		}
 	case "commit":
 		if m.commitSummary != nil {
@@ -143,12 +207,12 @@ func (m model) renderStatusBar() string {
 		}
 	case "branches":
 		helpText = k("j/k") + d(": nav") + sep + k("enter") + d(": checkout") + sep +
-			k("n") + d(": new") + sep + k("d") + d(": delete") + sep + k("c") + d(": compare")
+			k("n") + d(": new") + sep + k("d") + d(": delete") + sep + k("c") + d(": compare") + sep + k("C") + d(": compare refs")
 	case "tools":
 		switch m.toolMode {
 		case "stash":
 			helpText = k("j/k") + d(": nav") + sep + k("s") + d(": stash") + sep +
-				k("p") + d(": pop") + sep + k("a") + d(": apply") + sep + k("esc") + d(": back")
+				k("u/i/t") + d(": options") + sep + k("esc") + d(": back")
 		case "tags":
 			helpText = k("j/k") + d(": nav") + sep + k("n") + d(": new") + sep +
 				k("d") + d(": delete") + sep + k("p") + d(": push") + sep + k("esc") + d(": back")
@@ -192,6 +256,10 @@ func (m model) renderWorkspaceContent(width, height int) (string, string) {
 		return "", m.renderBlame(width, height)
 	}
 
+	if m.viewMode == "filelog" {
+		return "", m.renderFileHistory(width, height)
+	}
+
 	if m.viewMode == "conflicts" {
 		return "", m.renderConflictsList(width, height)
 	}
@@ -258,8 +326,11 @@ func (m model) renderDiffPane(width, height int) string {
 	if m.diffContent == "" {
 		headerText = "👁 Preview"
 		content = helpStyle.Render("Select a file to preview changes")
+	} else if m.diffLarge && !m.diffShowLarge {
+		headerText = "👁 Preview"
+		content = m.renderLargeDiffNotice()
 	} else {
-		lines := strings.Split(m.diffContent, "\n")
+		lines := renderDiffLines(m.diffContent, width-6, m.diffSplit)
 		maxLines := contentHeight
 		if maxLines < 1 {
 			maxLines = 1
@@ -282,6 +353,9 @@ func (m model) renderDiffPane(width, height int) string {
 			scrollInfo = helpStyle.Render(fmt.Sprintf("[%d/%d]", m.scrollOffset+1, len(lines)))
 		}
 		headerText = ("👁 Preview ") + scrollInfo
+		if label := diffOptsLabel(m.diffOpts); label != "" {
+			headerText += " " + helpStyle.Render(label)
+		}
 
 		// Apply scroll
 		startIdx := m.scrollOffset
@@ -298,15 +372,7 @@ func (m model) renderDiffPane(width, height int) string {
 			items = append(items, scrollIndicatorStyle.Render("▲"))
 		}
 
-		for i := startIdx; i < endIdx; i++ {
-			// Truncate long lines
-			line := lines[i]
-			maxLineWidth := width - 6
-			if len(line) > maxLineWidth {
-				line = line[:maxLineWidth-3] + "..."
-			}
-			items = append(items, colorizeDiffLine(line))
-		}
+		items = append(items, lines[startIdx:endIdx]...)
 
 		if hasBottom {
 			items = append(items, scrollIndicatorStyle.Render("▼"))
@@ -343,6 +409,9 @@ func (m model) renderFilePane(width, height int) string {
 		Width(width - 4)
 
 	header := headerStyle.Render(fmt.Sprintf("📄 Files"))
+	if len(m.stashMarks) > 0 {
+		header = headerStyle.Render(fmt.Sprintf("📄 Files (%d marked, z: stash)", len(m.stashMarks)))
+	}
 
 	// Calculate scroll - use most of content height for items
 	maxItems := contentHeight
@@ -373,19 +442,23 @@ func (m model) renderFilePane(width, height int) string {
 
 	for i := m.fileOffset; i < endIdx; i++ {
 		change := m.changes[i]
+		label := changeLabel(change)
+		if m.stashMarks[change.File] {
+			label = "✓ " + label
+		}
 
 		if i == m.fileCursor {
 			iconChar, iconColor := getStatusIconParts(change.Status)
 			selBg := lipgloss.Color("236")
 
 			iconPart := lipgloss.NewStyle().Foreground(iconColor).Background(selBg).Bold(true).Render(iconChar)
-			textPart := lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Background(selBg).Bold(true).Render(" " + change.File)
+			textPart := lipgloss.NewStyle().Foreground(lipgloss.Color("255")).Background(selBg).Bold(true).Render(" " + label)
 
 			line := iconPart + textPart
 			items = append(items, lipgloss.NewStyle().Width(width-6).Background(selBg).Render(line))
 		} else {
 			icon := getStatusIcon(change.Status)
-			line := fmt.Sprintf("%s %s", icon, change.File)
+			line := fmt.Sprintf("%s %s", icon, label)
 			items = append(items, normalStyle.Render(line))
 		}
 	}
@@ -411,8 +484,11 @@ func (m model) renderDiff(width, height int) string {
 	if m.diffContent == "" {
 		return helpStyle.Render("No diff to display")
 	}
+	if m.diffLarge && !m.diffShowLarge {
+		return m.renderLargeDiffNotice()
+	}
 
-	lines := strings.Split(m.diffContent, "\n")
+	lines := renderDiffLines(m.diffContent, width-2, m.diffSplit)
 
 	// Apply scroll
 	maxLines := height - 2
@@ -436,7 +512,7 @@ func (m model) renderDiff(width, height int) string {
 	}
 
 	for i := m.scrollOffset; i < endIdx; i++ {
-		result = append(result, colorizeDiffLine(lines[i]))
+		result = append(result, lines[i])
 	}
 
 	if hasBottom {
@@ -446,6 +522,15 @@ func (m model) renderDiff(width, height int) string {
 	return strings.Join(result, "\n")
 }
 
+// renderLargeDiffNotice stands in for a diff over the render limits
+func (m model) renderLargeDiffNotice() string {
+	size := float64(len(m.diffContent)) / 1024
+	lines := strings.Count(m.diffContent, "\n")
+	return warningStyle.Render("Diff too large") + "\n" +
+		helpStyle.Render(fmt.Sprintf("%d lines, %.0f KB", lines, size)) + "\n\n" +
+		keyBindStyle.Render("y") + keyDescStyle.Render(": show anyway")
+}
+
 func (m model) renderConflictsList(width, height int) string {
 	if len(m.conflicts) == 0 {
 		return helpStyle.Render("No conflicts found")
@@ -531,10 +616,7 @@ func (m model) renderCommitSummary(width, height int) string {
 	lines = append(lines, "")
 
 	lines = append(lines, lipgloss.NewStyle().Bold(true).Render("Diff:"))
-	diffLines := strings.Split(summary.diff, "\n")
-	for _, line := range diffLines {
-		lines = append(lines, colorizeDiffLine(line))
-	}
+	lines = append(lines, renderDiffLines(summary.diff, width-4, m.diffSplit)...)
 	lines = append(lines, "")
 
 	lines = append(lines, warningStyle.Render("Actions: [p] Push  [c] Continue  [1] Workspace"))
@@ -577,6 +659,11 @@ func (m model) renderBranchesContent(width, height int) (string, string) {
 		return "", m.branchInput.View()
 	}
 
+	if m.compareInput.Focused() {
+		return "", sectionHeaderStyle.Render("Compare refs") + "\n\n" + m.compareInput.View() + "\n\n" +
+			helpStyle.Render("target..source diffs the trees, target...source shows changes since the merge base")
+	}
+
 	if len(m.branches) == 0 {
 		return "", helpStyle.Render("Loading branches...")
 	}
@@ -673,31 +760,200 @@ func (m model) renderBranchList(width, height int) string {
 	return strings.Join(lines, "\n")
 }
 
+// compareCommitPreview is how many ahead/behind commits the comparison
+// lists before summarizing the rest
+const compareCommitPreview = 3
+
+// mergePredictionLine summarizes a dry-run merge or rebase in one line
+func mergePredictionLine(p git.MergePrediction, what string) string {
+	switch {
+	case p.Error != "":
+		return helpStyle.Render(what + " prediction unavailable: " + p.Error)
+	case p.Clean:
+		return successStyle.Render("✓ " + what + " would be clean")
+	}
+	paths := make([]string, len(p.Conflicts))
+	for i, c := range p.Conflicts {
+		paths[i] = c.Path
+	}
+	if len(paths) > 3 {
+		paths = append(paths[:3], fmt.Sprintf("+%d more", len(paths)-3))
+	}
+	label := what + " would conflict"
+	if p.Commit != "" {
+		label += " at " + git.ShortHash(p.Commit)
+	}
+	return warningStyle.Render(fmt.Sprintf("⚠ %s in %d files: ", label, len(p.Conflicts))) + strings.Join(paths, ", ") +
+		" " + keyBindStyle.Render("x") + keyDescStyle.Render(": details")
+}
+
+// compareHeader renders the comparison summary above the file list
+func (m model) compareHeader(width int) []string {
+	c := m.branchComparison
+	hashStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+
+	mode := "two-dot: " + c.TargetBranch + ".." + c.SourceBranch + ", difference between the trees"
+	if c.ThreeDot {
+		mode = "three-dot: " + c.TargetBranch + "..." + c.SourceBranch + ", changes since the merge base"
+	}
+	lines := []string{
+		sectionHeaderStyle.Render(c.SourceBranch+" vs "+c.TargetBranch) + " " + helpStyle.Render("("+mode+")"),
+	}
+	if c.MergeBase != nil {
+		lines = append(lines, "Merge base: "+hashStyle.Render(c.MergeBase.Hash)+" "+c.MergeBase.Message+" "+helpStyle.Render(c.MergeBase.Date))
+	} else {
+		lines = append(lines, helpStyle.Render("No merge base (unrelated histories)"))
+	}
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", max(0, width-6))))
+
+	commits := func(label string, list []git.Commit) {
+		lines = append(lines, fmt.Sprintf("%s: %d commits", label, len(list)))
+		for i, commit := range list {
+			if i == compareCommitPreview {
+				lines = append(lines, helpStyle.Render(fmt.Sprintf("  … %d more", len(list)-i)))
+				break
+			}
+			lines = append(lines, "  "+hashStyle.Render(commit.Hash)+" "+commit.Message)
+		}
+	}
+	commits("Ahead", c.AheadCommits)
+	commits("Behind", c.BehindCommits)
+
+	lines = append(lines, mergePredictionLine(c.Merge, "Merge"))
+
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", max(0, width-6))))
+	lines = append(lines, fmt.Sprintf("%d files changed, %s %s", len(c.Files),
+		diffAddStyle.Render(fmt.Sprintf("+%d", c.Insertions)), diffRemoveStyle.Render(fmt.Sprintf("-%d", c.Deletions))))
+	return lines
+}
+
 func (m model) renderBranchComparison(width, height int) string {
 	if m.branchComparison == nil {
 		return ""
 	}
+	if m.compareDiffPath != "" || m.compareConflicts {
+		return m.renderCompareDiff(width, height)
+	}
 
-	var lines []string
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
 
-	lines = append(lines, fmt.Sprintf("%s vs %s",
-		m.branchComparison.SourceBranch,
-		m.branchComparison.TargetBranch))
-	lines = append(lines, "")
+	files := m.branchComparison.Files
+	lines := m.compareHeader(width)
+
+	maxItems := height - len(lines) - 2
+	if maxItems < 1 {
+		maxItems = 1
+	}
+
+	hasTop := m.compareOffset > 0
+	hasBottom := m.compareOffset+maxItems < len(files)
+
+	if hasTop {
+		maxItems--
+		lines = append(lines, scrollIndicatorStyle.Render("  ▲ more above"))
+	}
+	if hasBottom {
+		maxItems--
+	}
+
+	endIdx := min(m.compareOffset+maxItems, len(files))
+	for i := m.compareOffset; i < endIdx; i++ {
+		f := files[i]
+		stat := diffAddStyle.Render(fmt.Sprintf("%5s", fmt.Sprintf("+%d", f.Added))) + " " +
+			diffRemoveStyle.Render(fmt.Sprintf("%-5s", fmt.Sprintf("-%d", f.Deleted)))
+		if f.Binary {
+			stat = helpStyle.Render(fmt.Sprintf("%-11s", "  binary"))
+		}
+		name := f.Path
+		if f.OrigPath != "" {
+			name = f.OrigPath + " → " + f.Path
+		}
+		line := stat + " " + name
+		for _, c := range m.branchComparison.Merge.Conflicts {
+			if c.Path == f.Path {
+				line += " " + warningStyle.Render("⚠ conflict")
+			}
+		}
+
+		if i == m.compareCursor {
+			lines = append(lines, selectedStyle.Width(width-4).Render(line))
+		} else {
+			lines = append(lines, line)
+		}
+	}
 
-	lines = append(lines, fmt.Sprintf("Ahead: %d commits", len(m.branchComparison.AheadCommits)))
-	for _, commit := range m.branchComparison.AheadCommits {
-		lines = append(lines, fmt.Sprintf("  %s %s", commit.Hash, commit.Message))
+	if hasBottom {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▼ more below"))
 	}
 
 	lines = append(lines, "")
-	lines = append(lines, fmt.Sprintf("Behind: %d commits", len(m.branchComparison.BehindCommits)))
-	for _, commit := range m.branchComparison.BehindCommits {
-		lines = append(lines, fmt.Sprintf("  %s %s", commit.Hash, commit.Message))
+	lines = append(lines, k("j/k")+d(": nav")+sep+k("enter")+d(": file diff")+sep+
+		k(".")+d(": two/three-dot")+sep+k("x")+d(": conflicts")+sep+k("p")+d(": pick behind")+sep+k("esc")+d(": back"))
+
+	return strings.Join(lines, "\n")
+}
+
+// renderCompareDiff shows one file's diff within the comparison
+func (m model) renderCompareDiff(width, height int) string {
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+
+	c := m.branchComparison
+	dots := ".."
+	if c.ThreeDot {
+		dots = "..."
+	}
+	header := sectionHeaderStyle.Render("Diff: "+m.compareDiffPath) + " " + helpStyle.Render(c.TargetBranch+dots+c.SourceBranch)
+	help := k("j/k") + d(": scroll") + sep + k("t") + d(": split/unified") + sep + k("esc") + d(": files")
+
+	var content []string
+	if m.compareConflicts {
+		header = sectionHeaderStyle.Render("Predicted conflicts") + " " + helpStyle.Render("merging "+c.TargetBranch+" into "+c.SourceBranch)
+		help = k("j/k") + d(": scroll") + sep + k("esc") + d(": files")
+		content = renderConflictLines(c.Merge.Conflicts, width-4)
+	} else if text := strings.TrimRight(m.compareDiff, "\n"); text != "" {
+		content = renderDiffLines(text, width-4, m.diffSplit)
+	} else {
+		content = []string{helpStyle.Render("(no changes to this file)")}
+	}
+
+	maxLines := height - 4
+	if maxLines < 1 {
+		maxLines = 1
+	}
+
+	hasTop := m.scrollOffset > 0
+	hasBottom := m.scrollOffset+maxLines < len(content)
+
+	if hasTop {
+		maxLines--
+	}
+	if hasBottom {
+		maxLines--
+	}
+
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	if hasTop {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▲ more above"))
+	}
+
+	endIdx := min(m.scrollOffset+maxLines, len(content))
+	for i := min(m.scrollOffset, len(content)); i < endIdx; i++ {
+		lines = append(lines, content[i])
+	}
+
+	if hasBottom {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▼ more below"))
 	}
 
 	lines = append(lines, "")
-	lines = append(lines, fmt.Sprintf("Files changed: %d", len(m.branchComparison.DifferingFiles)))
+	lines = append(lines, help)
 
 	return strings.Join(lines, "\n")
 }
@@ -711,8 +967,8 @@ func (m model) renderToolsContent(width, height int) (string, string) {
 		return "", m.renderUndoList(width, height)
 	case "rebase":
 		return "", m.renderRebaseContent(width, height)
-	case "history":
-		return "", m.renderHistoryList(width, height)
+	case "reflog":
+		return "", m.renderReflogContent(width, height)
 	case "remote":
 		return "", m.renderRemoteContent(width, height)
 	case "stash":
@@ -727,6 +983,12 @@ func (m model) renderToolsContent(width, height int) (string, string) {
 		return "", m.renderInitContent(width, height)
 	case "clean":
 		return "", m.renderCleanContent(width, height)
+	case "operation":
+		return "", m.renderOperationPanel(width, height)
+	case "bisect":
+		return "", m.renderBisectContent(width, height)
+	case "cherrypick":
+		return "", m.renderCherryPickContent(width, height)
 	default:
 		return "", m.renderToolsMenu(width, height)
 	}
@@ -742,7 +1004,7 @@ func (m model) renderToolsMenu(width, height int) string {
 		{"o", "📜", "Log", "Browse commit history"},
 		{"s", "📦", "Stash", "Save/restore work in progress"},
 		{"t", "🏷️", "Tags", "Manage version tags"},
-		{"h", "📜", "History", "View reflog"},
+		{"h", "📜", "Reflog", "Recover lost commits and branches"},
 		{"u", "⏪", "Undo", "Undo recent commits"},
 		{"r", "📝", "Rebase", "Interactive rebase"},
 		{"p", "⬆️", "Push", "Push to remote"},
@@ -751,6 +1013,7 @@ func (m model) renderToolsMenu(width, height int) string {
 		{"x", "🧹", "Clean", "Remove untracked files"},
 		{"c", "📥", "Clone", "Clone a repository"},
 		{"i", "🆕", "Init", "Initialize new repo"},
+		{"b", "🔍", "Bisect", "Find the commit that introduced a bug"},
 	}
 
 	var lines []string
@@ -800,20 +1063,57 @@ func (m model) renderToolsMenu(width, height int) string {
 	return strings.Join(lines, "\n")
 }
 
+// resetModeHints say what each reset mode does with the undone changes
+var resetModeHints = map[git.ResetMode]string{
+	git.ResetSoft:  "undone commits become staged changes",
+	git.ResetMixed: "undone commits and staged changes become unstaged",
+	git.ResetHard:  "undone commits and all uncommitted changes are discarded",
+	git.ResetKeep:  "undone commits are discarded, uncommitted changes kept",
+}
+
+// undoListHeight is how many rows of the undo view the commit list gets;
+// the reset preview takes the rest
+func undoListHeight(height int) int {
+	return max(3, (height-5)*2/5)
+}
+
 func (m model) renderUndoList(width, height int) string {
-	commits := m.commits
-	if len(commits) == 0 {
-		return helpStyle.Render("No commits to undo")
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+	hashStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+
+	var modes []string
+	for _, mode := range git.ResetModes {
+		if mode == m.resetMode {
+			modes = append(modes, branchCurrentStyle.Render("["+string(mode)+"]"))
+		} else {
+			modes = append(modes, helpStyle.Render(string(mode)))
+		}
+	}
+	header := sectionHeaderStyle.Render("Undo") + " " + strings.Join(modes, " ") + " " +
+		helpStyle.Render("- "+resetModeHints[m.resetMode])
+	help := k("j/k") + d(": nav") + sep + k("m") + d(": mode") + sep + k("enter") + d(": reset here") + sep + k("esc") + d(": back")
+	if m.confirmAction == "reset-hard" {
+		help = k("y") + d(": discard and reset") + sep + k("any other key") + d(": cancel")
 	}
 
-	maxItems := height - 2
-	if maxItems < 1 {
-		maxItems = 1
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	commits := m.commits
+	if len(commits) == 0 {
+		lines = append(lines, helpStyle.Render("No commits to undo"))
+		lines = append(lines, "")
+		lines = append(lines, help)
+		return strings.Join(lines, "\n")
 	}
 
+	listHeight := undoListHeight(height)
+	maxItems := listHeight
 	hasTop := m.undoOffset > 0
-	hasBottom := m.undoOffset+maxItems < len(commits)
-
+	hasBottom := m.undoOffset+maxItems < len(commits) || !m.commitsPager.done
 	if hasTop {
 		maxItems--
 	}
@@ -821,77 +1121,146 @@ func (m model) renderUndoList(width, height int) string {
 		maxItems--
 	}
 
-	var lines []string
-
+	var list []string
 	if hasTop {
-		lines = append(lines, scrollIndicatorStyle.Render("more above..."))
-	}
-
-	endIdx := m.undoOffset + maxItems
-	if endIdx > len(commits) {
-		endIdx = len(commits)
+		list = append(list, scrollIndicatorStyle.Render("  ▲ more above"))
 	}
-
+	endIdx := min(m.undoOffset+maxItems, len(commits))
 	for i := m.undoOffset; i < endIdx; i++ {
 		commit := commits[i]
-		line := fmt.Sprintf("%s %s (%s)", commit.Hash, commit.Message, commit.Date)
-
+		line := fmt.Sprintf(" %s %s %s", hashStyle.Render(commit.Hash), commit.Message, helpStyle.Render(commit.Date))
 		if i == m.undoCursor {
-			lines = append(lines, selectedStyle.Width(width-4).Render(line))
+			list = append(list, selectedStyle.Width(width-4).Render(line))
 		} else {
-			lines = append(lines, normalStyle.Render(line))
+			list = append(list, line)
 		}
 	}
-
 	if hasBottom {
-		lines = append(lines, scrollIndicatorStyle.Render("more below..."))
+		list = append(list, scrollIndicatorStyle.Render("  ▼ more below"))
+	}
+	// Keep the preview in place while the list scrolls
+	for len(list) < listHeight {
+		list = append(list, "")
 	}
+	lines = append(lines, list...)
+
+	lines = append(lines, "")
+	lines = append(lines, m.renderResetPreview(width, max(1, height-5-listHeight))...)
+	lines = append(lines, "")
+	lines = append(lines, help)
 
 	return strings.Join(lines, "\n")
 }
 
-func (m model) renderRebaseContent(width, height int) string {
-	if m.rebaseInput.Focused() {
-		return "Enter number of commits: " + m.rebaseInput.View()
+// renderResetPreview lists the commits and files a reset to the selected
+// commit affects in the current mode, or, while a hard reset waits for y,
+// the dirty files it loses
+func (m model) renderResetPreview(width, maxLines int) []string {
+	p := m.resetPreview
+	if p == nil {
+		return []string{helpStyle.Render("Working out what the reset changes...")}
 	}
+	hashStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
 
-	if len(m.rebaseCommits) == 0 {
-		return helpStyle.Render("Enter number of commits (1-50)")
+	if m.confirmAction == "reset-hard" {
+		lines := []string{errorStyle.Render(fmt.Sprintf("⚠ A hard reset discards the uncommitted changes in %d files:", len(p.Dirty)))}
+		shown := min(len(p.Dirty), max(1, maxLines-1))
+		if shown < len(p.Dirty) {
+			shown--
+		}
+		for _, f := range p.Dirty[:max(0, shown)] {
+			lines = append(lines, "   "+runewidth.Truncate(f, width-8, "…"))
+		}
+		if shown < len(p.Dirty) {
+			lines = append(lines, helpStyle.Render(fmt.Sprintf("   … %d more", len(p.Dirty)-shown)))
+		}
+		return lines
 	}
 
-	var lines []string
-	for i, commit := range m.rebaseCommits {
-		action := commit.Action
-		if action == "" {
-			action = "pick"
+	branch := m.gitState.Branch
+	if branch == "" {
+		branch = "HEAD"
+	}
+	effect := p.Effect(m.resetMode)
+
+	var groups []string
+	addGroup := func(label string, style lipgloss.Style, files []string) {
+		if len(files) == 0 {
+			return
 		}
-		line := fmt.Sprintf("[%s] %s %s", action, commit.Hash, commit.Message)
+		prefix := fmt.Sprintf("%s (%d): ", label, len(files))
+		text := runewidth.Truncate(strings.Join(files, ", "), max(10, width-8-len(prefix)), "…")
+		groups = append(groups, style.Render(prefix)+text)
+	}
+	addGroup("Staged", successStyle, effect.Staged)
+	addGroup("Unstaged", warningStyle, effect.Unstaged)
+	addGroup("Discarded", errorStyle, effect.Discarded)
+	addGroup("Blocked by local changes", errorStyle, effect.Blocked)
+	if len(groups) == 0 {
+		groups = append(groups, helpStyle.Render("No file changes"))
+	}
 
-		if i == m.rebaseCursor {
-			lines = append(lines, selectedStyle.Width(width-4).Render(line))
-		} else {
-			lines = append(lines, normalStyle.Render(line))
+	var lines []string
+	if len(p.Commits) == 0 {
+		lines = append(lines, helpStyle.Render("No commits leave "+branch))
+	} else {
+		lines = append(lines, fmt.Sprintf("%d commits leave %s:", len(p.Commits), branch))
+		budget := max(1, maxLines-1-len(groups))
+		shown := min(len(p.Commits), budget)
+		if shown < len(p.Commits) {
+			shown = max(0, shown-1)
+		}
+		for _, c := range p.Commits[:shown] {
+			lines = append(lines, "   "+hashStyle.Render(c.Hash)+" "+runewidth.Truncate(c.Message, width-20, "…"))
+		}
+		if shown < len(p.Commits) {
+			lines = append(lines, helpStyle.Render(fmt.Sprintf("   … %d more", len(p.Commits)-shown)))
 		}
 	}
+	return append(lines, groups...)
+}
 
-	lines = append(lines, "")
-	lines = append(lines, helpStyle.Render("p=pick s=squash r=reword d=drop enter=execute"))
+func (m model) renderRebaseContent(width, height int) string {
+	if m.rebaseState != nil {
+		return m.renderRebasePanel(width, height)
+	}
+	if m.rebaseInput.Focused() {
+		return "Enter number of commits: " + m.rebaseInput.View()
+	}
 
-	return strings.Join(lines, "\n")
-}
+	if len(m.rebaseCommits) == 0 {
+		return helpStyle.Render("Enter number of commits to rebase")
+	}
+
+	if m.rebaseConflicts && m.rebasePrediction != nil {
+		return m.renderRebaseConflicts(width, height)
+	}
+
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
 
-func (m model) renderHistoryList(width, height int) string {
-	if len(m.commits) == 0 {
-		return helpStyle.Render("Loading history...")
+	var footer []string
+	if m.rebasePrediction != nil {
+		footer = append(footer, mergePredictionLine(*m.rebasePrediction, "Rebase"))
+	}
+	footer = append(footer, rebaseOptionsLine(m.rebaseOpts))
+	if m.rebaseEdit.Focused() {
+		labels := map[string]string{"reword": "Message", "exec": "Exec", "onto": "Onto"}
+		footer = append(footer, "", labels[m.rebaseField]+": "+m.rebaseEdit.View(), helpStyle.Render("enter: save | esc: cancel"))
+	} else {
+		footer = append(footer, "",
+			k("p/s/f/e/d")+d(": pick/squash/fixup/edit/drop")+sep+k("r")+d(": reword")+sep+k("J/K")+d(": move")+sep+k("!")+d(": exec"),
+			k("o")+d(": onto")+sep+k("a")+d(": autosquash")+sep+k("u")+d(": update-refs")+sep+k("enter")+d(": check, then execute"))
 	}
 
-	maxItems := height - 2
+	maxItems := height - 2 - len(footer)
 	if maxItems < 1 {
 		maxItems = 1
 	}
 
-	hasTop := m.historyOffset > 0
-	hasBottom := m.historyOffset+maxItems < len(m.commits)
+	hasTop := m.rebaseOffset > 0
+	hasBottom := m.rebaseOffset+maxItems < len(m.rebaseCommits)
 
 	if hasTop {
 		maxItems--
@@ -906,20 +1275,28 @@ func (m model) renderHistoryList(width, height int) string {
 		lines = append(lines, scrollIndicatorStyle.Render("more above..."))
 	}
 
-	endIdx := m.historyOffset + maxItems
-	if endIdx > len(m.commits) {
-		endIdx = len(m.commits)
+	endIdx := m.rebaseOffset + maxItems
+	if endIdx > len(m.rebaseCommits) {
+		endIdx = len(m.rebaseCommits)
 	}
 
-	for i := m.historyOffset; i < endIdx; i++ {
-		commit := m.commits[i]
-		line := fmt.Sprintf("%s %s (%s - %s)",
-			lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Render(commit.Hash),
-			commit.Message,
-			commit.Author,
-			commit.Date)
+	for i := m.rebaseOffset; i < endIdx; i++ {
+		commit := m.rebaseCommits[i]
+		action := commit.Action
+		if action == "" {
+			action = "pick"
+		}
+		var line string
+		switch {
+		case action == "exec":
+			line = fmt.Sprintf("[exec] $ %s", commit.Command)
+		case action == "reword" && commit.NewMessage != "":
+			line = fmt.Sprintf("[%s] %s %s (was: %s)", action, commit.Hash, commit.NewMessage, commit.Message)
+		default:
+			line = fmt.Sprintf("[%s] %s %s", action, commit.Hash, commit.Message)
+		}
 
-		if i == m.historyCursor {
+		if i == m.rebaseCursor {
 			lines = append(lines, selectedStyle.Width(width-4).Render(line))
 		} else {
 			lines = append(lines, normalStyle.Render(line))
@@ -930,72 +1307,281 @@ func (m model) renderHistoryList(width, height int) string {
 		lines = append(lines, scrollIndicatorStyle.Render("more below..."))
 	}
 
-	return strings.Join(lines, "\n")
-}
-
-func (m model) renderRemoteContent(width, height int) string {
-	if m.pushOutput != "" {
-		return m.pushOutput
-	}
-
-	var lines []string
-	lines = append(lines, "[p] Push to origin")
-	lines = append(lines, "[f] Fetch from origin")
-	lines = append(lines, "[l] Pull from origin")
+	lines = append(lines, footer...)
 
 	return strings.Join(lines, "\n")
 }
 
-func (m model) renderStashList(width, height int) string {
+// renderRebasePanel shows a stopped rebase: where it is, why it stopped
+// and what is left
+func (m model) renderRebasePanel(width, height int) string {
 	k := func(key string) string { return keyBindStyle.Render(key) }
 	d := func(desc string) string { return keyDescStyle.Render(desc) }
 	sep := keyDescStyle.Render(" | ")
+	fit := func(s string) string { return runewidth.Truncate(s, width-6, "…") }
 
-	header := sectionHeaderStyle.Render("Stash List")
-	help := k("s") + d(": stash") + sep + k("p/enter") + d(": pop") + sep +
-		k("a") + d(": apply") + sep + k("d") + d(": drop")
-
-	if len(m.stashes) == 0 {
-		return header + "\n" + helpStyle.Render(strings.Repeat("─", width-6)) + "\n\n" +
-			helpStyle.Render("No stashes. Press 's' to stash current changes.") + "\n\n" + help
-	}
-
-	maxItems := height - 4
-	if maxItems < 1 {
-		maxItems = 1
+	s := m.rebaseState
+	branch := s.HeadName
+	if branch == "" {
+		branch = "detached HEAD"
 	}
+	header := sectionHeaderStyle.Render(fmt.Sprintf("Rebase in progress: step %d/%d", s.Step, s.Total)) + " " +
+		helpStyle.Render(fmt.Sprintf("%s onto %s", strings.TrimPrefix(branch, "refs/heads/"), s.Onto))
 
-	hasTop := m.stashOffset > 0
-	hasBottom := m.stashOffset+maxItems < len(m.stashes)
-
-	if hasTop {
-		maxItems--
+	var help []string
+	help = append(help, k("c")+d(": continue"), k("s")+d(": skip commit"))
+	if s.Interactive {
+		help = append(help, k("e")+d(": edit todo"))
 	}
-	if hasBottom {
-		maxItems--
+	if len(s.Conflicts) > 0 {
+		help = append(help, k("x")+d(": resolve conflicts"))
 	}
+	help = append(help, k("a")+d(": abort"), k("r")+d(": refresh"), k("esc")+d(": back"))
 
 	var lines []string
 	lines = append(lines, header)
 	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
 
-	if hasTop {
-		lines = append(lines, scrollIndicatorStyle.Render("  ▲ more above"))
+	switch {
+	case len(s.Conflicts) > 0:
+		lines = append(lines, warningStyle.Render(fmt.Sprintf("⚠ Stopped on conflicts in %d files: ", len(s.Conflicts)))+
+			fit(strings.Join(s.Conflicts, ", ")))
+	case s.StoppedAt != "":
+		lines = append(lines, fmt.Sprintf("Stopped at %s: amend it, then continue", s.StoppedAt))
+	default:
+		lines = append(lines, "Stopped: fix what's needed, then continue")
 	}
 
-	endIdx := m.stashOffset + maxItems
-	if endIdx > len(m.stashes) {
-		endIdx = len(m.stashes)
-	}
+	// The rest of the height is split between done and remaining items,
+	// keeping the current item and the next ones in view
+	budget := max(2, height-len(lines)-5)
+	doneShown := min(len(s.Done), max(1, budget/3))
+	todoShown := min(len(s.Todo), max(1, budget-doneShown))
 
-	for i := m.stashOffset; i < endIdx; i++ {
-		stash := m.stashes[i]
-		line := fmt.Sprintf(" 📦 stash@{%d}: %s  %s",
-			stash.Index,
-			stash.Message,
-			helpStyle.Render(stash.Date))
+	if len(s.Done) > 0 {
+		lines = append(lines, "", sectionHeaderStyle.Render(fmt.Sprintf("Done (%d)", len(s.Done))))
+		if doneShown < len(s.Done) {
+			lines = append(lines, scrollIndicatorStyle.Render(fmt.Sprintf("  … %d earlier", len(s.Done)-doneShown)))
+		}
+		for i, line := range s.Done[len(s.Done)-doneShown:] {
+			if i == doneShown-1 {
+				lines = append(lines, selectedStyle.Render(fit("▶ "+line)))
+			} else {
+				lines = append(lines, normalStyle.Render(fit("  "+line)))
+			}
+		}
+	}
 
-		if i == m.stashCursor {
+	if s.Interactive {
+		lines = append(lines, "", sectionHeaderStyle.Render(fmt.Sprintf("Remaining (%d)", len(s.Todo))))
+		if len(s.Todo) == 0 {
+			lines = append(lines, helpStyle.Render("  nothing left: continue finishes the rebase"))
+		}
+		for _, line := range s.Todo[:todoShown] {
+			lines = append(lines, normalStyle.Render(fit("  "+line)))
+		}
+		if todoShown < len(s.Todo) {
+			lines = append(lines, scrollIndicatorStyle.Render(fmt.Sprintf("  … %d more", len(s.Todo)-todoShown)))
+		}
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, strings.Join(help, sep))
+
+	return strings.Join(lines, "\n")
+}
+
+// renderOperationPanel shows the merge, cherry-pick or revert in progress
+// and its actions
+func (m model) renderOperationPanel(width, height int) string {
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+
+	op := m.operation
+	if op.Op == git.OpNone {
+		return helpStyle.Render("No operation in progress")
+	}
+
+	header := sectionHeaderStyle.Render(operationNames[op.Op] + " in progress")
+	switch {
+	case op.Op == git.OpMerge && op.Subject != "":
+		header += " " + helpStyle.Render(op.Subject)
+	case op.Head != "":
+		header += " " + helpStyle.Render(op.Head+" "+op.Subject)
+	}
+
+	help := []string{k("c") + d(": continue")}
+	if op.Op == git.OpCherryPick || op.Op == git.OpRevert {
+		help = append(help, k("s")+d(": skip commit"))
+	}
+	if len(op.Conflicts) > 0 {
+		help = append(help, k("x")+d(": resolve conflicts"))
+	}
+	help = append(help, k("a")+d(": abort"), k("r")+d(": refresh"), k("esc")+d(": back"))
+
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	if len(op.Conflicts) > 0 {
+		lines = append(lines, warningStyle.Render(fmt.Sprintf("⚠ Conflicts in %d files:", len(op.Conflicts))))
+		maxItems := max(1, height-len(lines)-3)
+		for i, path := range op.Conflicts {
+			if i == maxItems-1 && len(op.Conflicts) > maxItems {
+				lines = append(lines, scrollIndicatorStyle.Render(fmt.Sprintf("  … %d more", len(op.Conflicts)-i)))
+				break
+			}
+			lines = append(lines, normalStyle.Render(runewidth.Truncate("  "+path, width-6, "…")))
+		}
+	} else {
+		lines = append(lines, "No conflicts left: continue to commit")
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, strings.Join(help, sep))
+
+	return strings.Join(lines, "\n")
+}
+
+// renderBisectContent shows the bisect in progress: the commit to test, the
+// marks so far and the output of a bisect run
+func (m model) renderBisectContent(width, height int) string {
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+
+	state := m.bisectState
+	if state == nil {
+		var lines []string
+		lines = append(lines, sectionHeaderStyle.Render("Bisect"))
+		lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+		lines = append(lines, "No bisect in progress.")
+		lines = append(lines, helpStyle.Render("In the log, press b on a commit with the bug and g on one without it."))
+		lines = append(lines, "")
+		lines = append(lines, k("o")+d(": open log")+sep+k("esc")+d(": back"))
+		return strings.Join(lines, "\n")
+	}
+
+	header := sectionHeaderStyle.Render("Bisecting")
+	switch {
+	case state.FirstBad != nil:
+		header += " " + successStyle.Render("found")
+	case state.Candidates > 0 && len(state.Skipped) > 0:
+		// git's estimate assumes every commit can be tested
+		header += " " + helpStyle.Render(fmt.Sprintf("~%d+ steps left (%d revisions, %d skipped)",
+			state.Steps, state.Candidates, len(state.Skipped)))
+	case state.Candidates > 0:
+		header += " " + helpStyle.Render(fmt.Sprintf("~%d steps left (%d revisions)", state.Steps, state.Candidates))
+	}
+
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	row := func(label, value string) string {
+		return runewidth.Truncate(fmt.Sprintf("%-11s%s", label, value), width-6, "…")
+	}
+	if state.FirstBad != nil {
+		lines = append(lines, errorStyle.Render(row("First bad", state.FirstBad.Hash+" "+state.FirstBad.Message)))
+		lines = append(lines, helpStyle.Render(row("", state.FirstBad.Author+", "+state.FirstBad.Date)))
+	} else if state.Current != nil {
+		lines = append(lines, selectedStyle.Render(row("Testing", state.Current.Hash+" "+state.Current.Message)))
+	}
+	lines = append(lines, normalStyle.Render(row("Bad", state.Bad)))
+	lines = append(lines, normalStyle.Render(row("Good", strings.Join(state.Good, " "))))
+	if len(state.Skipped) > 0 {
+		lines = append(lines, normalStyle.Render(row("Skipped", strings.Join(state.Skipped, " "))))
+	}
+
+	if m.bisectInput.Focused() {
+		lines = append(lines, "")
+		lines = append(lines, "Run at every step (exit 0 good, 125 skip, other bad):")
+		lines = append(lines, m.bisectInput.View())
+	}
+
+	if len(m.bisectOutput) > 0 {
+		lines = append(lines, "")
+		maxItems := max(1, height-len(lines)-3)
+		output := m.bisectOutput
+		if len(output) > maxItems {
+			output = output[len(output)-maxItems:]
+		}
+		for _, line := range output {
+			lines = append(lines, helpStyle.Render(runewidth.Truncate(line, width-6, "…")))
+		}
+	}
+
+	var help []string
+	switch {
+	case m.bisectRun != nil:
+		help = []string{k("x") + d(": stop run")}
+	case m.bisectInput.Focused():
+		help = []string{k("enter") + d(": run"), k("esc") + d(": cancel")}
+	default:
+		if state.FirstBad != nil {
+			help = append(help, k("enter")+d(": show commit"))
+		} else {
+			help = append(help, k("g")+d(": good"), k("b")+d(": bad"), k("s")+d(": skip"), k("r")+d(": run command"))
+		}
+		help = append(help, k("a")+d(": end bisect"), k("esc")+d(": back"))
+	}
+	lines = append(lines, "")
+	lines = append(lines, strings.Join(help, sep))
+
+	return strings.Join(lines, "\n")
+}
+
+// renderCherryPickContent lists the commits queued for a cherry-pick, oldest
+// first, with the flags it will run with
+func (m model) renderCherryPickContent(width, height int) string {
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+	hashStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+
+	selected := 0
+	for _, s := range m.pickSelected {
+		if s {
+			selected++
+		}
+	}
+	onto := m.gitState.Branch
+	if onto == "" {
+		onto = "HEAD"
+	}
+	header := sectionHeaderStyle.Render("Cherry-pick") + " " +
+		helpStyle.Render(fmt.Sprintf("%d of %d commits onto %s, oldest first", selected, len(m.pickCommits), onto))
+
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	maxItems := max(1, height-5)
+	hasTop := m.pickOffset > 0
+	hasBottom := m.pickOffset+maxItems < len(m.pickCommits)
+	if hasTop {
+		maxItems--
+		lines = append(lines, scrollIndicatorStyle.Render("  ▲ more above"))
+	}
+	if hasBottom {
+		maxItems--
+	}
+
+	endIdx := min(m.pickOffset+maxItems, len(m.pickCommits))
+	for i := m.pickOffset; i < endIdx; i++ {
+		commit := m.pickCommits[i]
+		check := "[ ]"
+		if m.pickSelected[i] {
+			check = "[x]"
+		}
+		line := fmt.Sprintf(" %s %s %s", check, hashStyle.Render(commit.Hash), commit.Message)
+		if len(commit.Parents) > 1 {
+			line += " " + warningStyle.Render("merge")
+		}
+		line += "  " + helpStyle.Render(commit.Author+", "+commit.Date)
+
+		if i == m.pickCursor {
 			lines = append(lines, selectedStyle.Width(width-4).Render(line))
 		} else {
 			lines = append(lines, line)
@@ -1006,12 +1592,288 @@ func (m model) renderStashList(width, height int) string {
 		lines = append(lines, scrollIndicatorStyle.Render("  ▼ more below"))
 	}
 
+	onOff := func(on bool) string {
+		if on {
+			return "on"
+		}
+		return "off"
+	}
+	options := fmt.Sprintf("Record origin (-x): %s   No commit (-n): %s", onOff(m.pickOpts.RecordOrigin), onOff(m.pickOpts.NoCommit))
+	help := k("space") + d(": toggle") + sep + k("a") + d(": all/none") + sep + k("x") + d(": -x") + sep + k("n") + d(": -n") + sep
+	if m.pickMergeParents() > 0 {
+		options += fmt.Sprintf("   Mainline (-m): parent %d", m.pickOpts.Mainline)
+		help += k("m") + d(": mainline") + sep
+	}
+	help += k("enter") + d(": pick") + sep + k("esc") + d(": back")
+
+	lines = append(lines, "")
+	lines = append(lines, helpStyle.Render(options))
+	lines = append(lines, help)
+
+	return strings.Join(lines, "\n")
+}
+
+// rebaseOptionsLine shows where the plan is replayed and the rebase flags
+func rebaseOptionsLine(opts git.RebaseOptions) string {
+	onto := "in place"
+	if opts.Onto != "" {
+		onto = opts.Onto
+	}
+	onOff := func(on bool) string {
+		if on {
+			return "on"
+		}
+		return "off"
+	}
+	return helpStyle.Render(fmt.Sprintf("Onto: %s · autosquash: %s · update-refs: %s",
+		onto, onOff(opts.Autosquash), onOff(opts.UpdateRefs)))
+}
+
+// renderRebaseConflicts shows the files and hunks the rebase plan would
+// stop on
+func (m model) renderRebaseConflicts(width, height int) string {
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+
+	p := m.rebasePrediction
+	header := sectionHeaderStyle.Render("Predicted conflicts") + " " + helpStyle.Render("rebase stops at "+git.ShortHash(p.Commit))
+	help := k("j/k") + d(": scroll") + sep + k("enter") + d(": rebase anyway") + sep + k("esc") + d(": back to plan")
+	content := renderConflictLines(p.Conflicts, width-4)
+
+	maxLines := height - 4
+	if maxLines < 1 {
+		maxLines = 1
+	}
+
+	hasTop := m.scrollOffset > 0
+	hasBottom := m.scrollOffset+maxLines < len(content)
+
+	if hasTop {
+		maxLines--
+	}
+	if hasBottom {
+		maxLines--
+	}
+
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	if hasTop {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▲ more above"))
+	}
+
+	endIdx := min(m.scrollOffset+maxLines, len(content))
+	for i := min(m.scrollOffset, len(content)); i < endIdx; i++ {
+		lines = append(lines, content[i])
+	}
+
+	if hasBottom {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▼ more below"))
+	}
+
 	lines = append(lines, "")
 	lines = append(lines, help)
 
 	return strings.Join(lines, "\n")
 }
 
+// reflogKindStyles colors the reflog action by what moved the ref
+var reflogKindStyles = map[string]lipgloss.Style{
+	"commit":      lipgloss.NewStyle().Foreground(lipgloss.Color("42")),
+	"merge":       lipgloss.NewStyle().Foreground(lipgloss.Color("42")),
+	"cherry-pick": lipgloss.NewStyle().Foreground(lipgloss.Color("42")),
+	"revert":      lipgloss.NewStyle().Foreground(lipgloss.Color("42")),
+	"reset":       lipgloss.NewStyle().Foreground(lipgloss.Color("203")),
+	"rebase":      lipgloss.NewStyle().Foreground(lipgloss.Color("214")),
+	"pull":        lipgloss.NewStyle().Foreground(lipgloss.Color("75")),
+	"checkout":    lipgloss.NewStyle().Foreground(lipgloss.Color("75")),
+}
+
+// renderReflogContent lists where HEAD or a branch has pointed, newest first
+func (m model) renderReflogContent(width, height int) string {
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+
+	header := sectionHeaderStyle.Render("Reflog") + " " + branchCurrentStyle.Render(m.reflogRef) +
+		" " + helpStyle.Render(fmt.Sprintf("(%d entries)", len(m.reflog)))
+	help := k("enter") + d(": detail") + sep + k("n") + d(": branch here") + sep + k("R") + d(": reset here") + sep +
+		k("c") + d(": cherry-pick") + sep + k("b") + d(": HEAD/branches") + sep + k("esc") + d(": back")
+
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	if len(m.reflog) == 0 {
+		lines = append(lines, helpStyle.Render("No reflog entries for "+m.reflogRef))
+		lines = append(lines, "")
+		lines = append(lines, help)
+		return strings.Join(lines, "\n")
+	}
+
+	maxItems := max(1, height-5)
+	hasTop := m.reflogOffset > 0
+	hasBottom := m.reflogOffset+maxItems < len(m.reflog)
+	if hasTop {
+		maxItems--
+		lines = append(lines, scrollIndicatorStyle.Render("  ▲ more above"))
+	}
+	if hasBottom {
+		maxItems--
+	}
+
+	hashStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	endIdx := min(m.reflogOffset+maxItems, len(m.reflog))
+	for i := m.reflogOffset; i < endIdx; i++ {
+		entry := m.reflog[i]
+		action := fmt.Sprintf("%-16s", entry.Action)
+		if style, ok := reflogKindStyles[entry.Kind]; ok {
+			action = style.Render(action)
+		}
+		// Moves that aren't new commits name where they went; show what's there
+		text := entry.Message
+		if entry.Kind != "commit" && entry.Subject != entry.Message {
+			text += " " + helpStyle.Render("· "+entry.Subject)
+		}
+		line := fmt.Sprintf(" %s %s %s %s  %s",
+			helpStyle.Render(fmt.Sprintf("%-12s", entry.Selector)),
+			hashStyle.Render(entry.Hash),
+			action,
+			text,
+			helpStyle.Render(entry.Date))
+
+		if i == m.reflogCursor {
+			lines = append(lines, selectedStyle.Width(width-4).Render(line))
+		} else {
+			lines = append(lines, line)
+		}
+	}
+
+	if hasBottom {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▼ more below"))
+	}
+
+	lines = append(lines, "")
+	if m.reflogInput.Focused() && m.reflogCursor < len(m.reflog) {
+		lines = append(lines, fmt.Sprintf("New branch at %s: ", m.reflog[m.reflogCursor].Hash)+m.reflogInput.View())
+	} else {
+		lines = append(lines, help)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (m model) renderRemoteContent(width, height int) string {
+	if m.pushOutput != "" {
+		return m.pushOutput
+	}
+
+	var lines []string
+	lines = append(lines, "[p] Push to origin")
+	lines = append(lines, "[f] Fetch from origin")
+	lines = append(lines, "[l] Pull from origin")
+
+	return strings.Join(lines, "\n")
+}
+
+func (m model) renderStashList(width, height int) string {
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+
+	header := sectionHeaderStyle.Render("Stash List")
+	help := k("s") + d(": stash") + sep + k("m") + d(": with message") + sep + k("p/enter") + d(": pop") + sep +
+		k("a") + d(": apply") + sep + k("d") + d(": drop") + sep + k("r") + d(": rename") + sep + k("b") + d(": branch")
+
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	if len(m.stashes) == 0 {
+		lines = append(lines, "")
+		lines = append(lines, helpStyle.Render("No stashes. Press 's' to stash current changes."))
+	}
+
+	maxItems := height - 6
+	if maxItems < 1 {
+		maxItems = 1
+	}
+
+	hasTop := m.stashOffset > 0
+	hasBottom := m.stashOffset+maxItems < len(m.stashes)
+
+	if hasTop {
+		maxItems--
+	}
+	if hasBottom {
+		maxItems--
+	}
+
+	if hasTop {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▲ more above"))
+	}
+
+	endIdx := m.stashOffset + maxItems
+	if endIdx > len(m.stashes) {
+		endIdx = len(m.stashes)
+	}
+
+	for i := m.stashOffset; i < endIdx; i++ {
+		stash := m.stashes[i]
+		// "On main: message" shows as the branch and the message
+		text := stash.Message
+		if stash.Branch != "" {
+			_, rest, _ := strings.Cut(stash.Message, ": ")
+			if strings.HasPrefix(stash.Message, "WIP ") {
+				rest = "WIP " + rest
+			}
+			text = branchCurrentStyle.Render(stash.Branch) + " " + rest
+		}
+		line := fmt.Sprintf(" 📦 stash@{%d}: %s  %s",
+			stash.Index,
+			text,
+			helpStyle.Render(stash.Date))
+
+		if i == m.stashCursor {
+			lines = append(lines, selectedStyle.Width(width-4).Render(line))
+		} else {
+			lines = append(lines, line)
+		}
+	}
+
+	if hasBottom {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▼ more below"))
+	}
+
+	onOff := func(on bool) string {
+		if on {
+			return "on"
+		}
+		return "off"
+	}
+	options := fmt.Sprintf("Include untracked (u): %s   Keep index (i): %s   Staged only (t): %s",
+		onOff(m.stashOpts.IncludeUntracked), onOff(m.stashOpts.KeepIndex), onOff(m.stashOpts.Staged))
+
+	lines = append(lines, "")
+	lines = append(lines, helpStyle.Render(options))
+	if m.stashInput.Focused() {
+		prompt := "Stash message: "
+		switch m.stashField {
+		case "rename":
+			prompt = fmt.Sprintf("Rename stash@{%d}: ", m.stashCursor)
+		case "branch":
+			prompt = fmt.Sprintf("Branch from stash@{%d}: ", m.stashCursor)
+		}
+		lines = append(lines, prompt+m.stashInput.View())
+	} else {
+		lines = append(lines, help)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
 func (m model) renderTagsList(width, height int) string {
 	k := func(key string) string { return keyBindStyle.Render(key) }
 	d := func(desc string) string { return keyDescStyle.Render(desc) }
@@ -1068,7 +1930,7 @@ func (m model) renderTagsList(width, height int) string {
 
 		commitInfo := ""
 		if tag.Commit != "" {
-			commitInfo = lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Render(" " + tag.Commit[:7])
+			commitInfo = lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Render(" " + git.ShortHash(tag.Commit))
 		}
 
 		line := fmt.Sprintf(" %s %s%s  %s",
@@ -1145,21 +2007,16 @@ func (m model) renderHooksContent(width, height int) string {
 
 // Helper functions
 
-func colorizeDiffLine(line string) string {
-	if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
-		return diffAddStyle.Render(line)
-	}
-	if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
-		return diffRemoveStyle.Render(line)
+// changeLabel shows renames as "old → new" and marks submodules
+func changeLabel(change git.Change) string {
+	label := change.File
+	if change.OrigPath != "" {
+		label = change.OrigPath + " → " + change.File
 	}
-	if strings.HasPrefix(line, "@@") {
-		return diffHunkStyle.Render(line)
+	if change.IsSubmodule() {
+		label += " (submodule)"
 	}
-	if strings.HasPrefix(line, "diff ") || strings.HasPrefix(line, "index ") ||
-		strings.HasPrefix(line, "---") || strings.HasPrefix(line, "+++") {
-		return diffHeaderStyle.Render(line)
-	}
-	return line
+	return label
 }
 
 func getStatusIcon(status string) string {
@@ -1229,15 +2086,41 @@ func (m model) renderLogContent(width, height int) string {
 		searchInfo = helpStyle.Render(fmt.Sprintf(" (filter: %s)", m.logSearch))
 	}
 
+	if filter := m.logFilter.String(); filter != "" {
+		if m.logPreset != "" {
+			filter = m.logPreset + ": " + filter
+		}
+		searchInfo += helpStyle.Render(" [" + filter + "]")
+	}
+
+	if len(m.pickMarks) > 0 {
+		searchInfo += " " + successStyle.Render(fmt.Sprintf("✓ %d marked", len(m.pickMarks)))
+	}
+
 	header := sectionHeaderStyle.Render("Commit Log") + searchInfo
-	help := k("/") + d(": search") + sep + k("enter") + d(": detail") + sep +
+	help := k("/") + d(": search") + sep + k("f") + d(": filter") + sep + k("p/P") + d(": preset/save") + sep +
+		k("F") + d(": clear") + sep + k("enter") + d(": detail") + sep +
 		k("c") + d(": cherry-pick") + sep + k("R") + d(": revert") + sep + k("esc") + d(": back")
+	if len(m.pickMarks) > 0 {
+		help = k("space") + d(": mark") + sep + k("v") + d(": mark range") + sep + k("c") + d(": cherry-pick marked") + sep +
+			k("enter") + d(": detail") + sep + k("esc") + d(": clear marks")
+	}
 
 	if m.logSearchInput.Focused() {
 		return header + "\n" + helpStyle.Render(strings.Repeat("─", width-6)) + "\n\n" +
 			"Search: " + m.logSearchInput.View()
 	}
 
+	if m.logFilterInput.Focused() {
+		if m.logSavePreset {
+			return header + "\n" + helpStyle.Render(strings.Repeat("─", width-6)) + "\n\n" +
+				"Preset name: " + m.logFilterInput.View()
+		}
+		return header + "\n" + helpStyle.Render(strings.Repeat("─", width-6)) + "\n\n" +
+			"Filter: " + m.logFilterInput.View() + "\n\n" +
+			helpStyle.Render("author: committer: since: until: path: S:text G:regex merges no-merges first-parent")
+	}
+
 	if len(m.logCommits) == 0 {
 		return header + "\n" + helpStyle.Render(strings.Repeat("─", width-6)) + "\n\n" +
 			helpStyle.Render("No commits found.") + "\n\n" + help
@@ -1249,7 +2132,7 @@ func (m model) renderLogContent(width, height int) string {
 	}
 
 	hasTop := m.logOffset > 0
-	hasBottom := m.logOffset+maxItems < len(m.logCommits)
+	hasBottom := m.logOffset+maxItems < len(m.logCommits) || !m.logPager.done
 
 	if hasTop {
 		maxItems--
@@ -1271,13 +2154,28 @@ func (m model) renderLogContent(width, height int) string {
 		endIdx = len(m.logCommits)
 	}
 
+	// Keep the graph to a third of the pane so messages stay readable
+	maxLanes := max(1, width/6)
+
 	for i := m.logOffset; i < endIdx; i++ {
 		commit := m.logCommits[i]
 		hashStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
 		dateStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
 
-		line := fmt.Sprintf(" %s %s  %s",
+		graph := ""
+		if i < len(m.logGraph) {
+			graph = m.logGraph[i].render(maxLanes) + " "
+		}
+
+		mark := " "
+		if m.pickMarks[commit.Hash] {
+			mark = successStyle.Render("✓")
+		}
+		line := fmt.Sprintf("%s%s%s %s%s  %s",
+			mark,
+			graph,
 			hashStyle.Render(commit.Hash),
+			renderRefs(commit.Refs),
 			commit.Message,
 			dateStyle.Render(commit.Date))
 
@@ -1330,10 +2228,7 @@ func (m model) renderLogDetail(width, height int) string {
 	// Diff
 	if m.logDiff != "" {
 		lines = append(lines, lipgloss.NewStyle().Bold(true).Render("Diff:"))
-		diffLines := strings.Split(m.logDiff, "\n")
-		for _, dl := range diffLines {
-			lines = append(lines, colorizeDiffLine(dl))
-		}
+		lines = append(lines, renderDiffLines(m.logDiff, width-4, m.diffSplit)...)
 	}
 
 	// Apply scroll
@@ -1374,10 +2269,54 @@ func (m model) renderBlame(width, height int) string {
 	k := func(key string) string { return keyBindStyle.Render(key) }
 	d := func(desc string) string { return keyDescStyle.Render(desc) }
 
+	sep := keyDescStyle.Render(" | ")
+
 	header := sectionHeaderStyle.Render("Blame: " + m.blameFile)
-	help := k("j/k") + d(": nav") + " | " + k("esc") + d(": back")
+	if m.blameOpts.Rev != "" {
+		header += lipgloss.NewStyle().Foreground(lipgloss.Color("39")).Render(" @ " + git.ShortHash(m.blameOpts.Rev))
+	}
+	var flags []string
+	if m.blameOpts.IgnoreWhitespace {
+		flags = append(flags, "-w")
+	}
+	if m.blameOpts.DetectMoves {
+		flags = append(flags, "-M")
+	}
+	if m.blameOpts.DetectCopies {
+		flags = append(flags, "-C")
+	}
+	if m.blameIgnoreRevs != "" {
+		flags = append(flags, "ignoring revs in "+m.blameIgnoreRevs)
+	}
+	if len(flags) > 0 {
+		header += helpStyle.Render(" (" + strings.Join(flags, ", ") + ")")
+	}
 
-	maxItems := height - 4
+	back := "back"
+	if len(m.blameStack) > 0 {
+		back = "newer revision"
+	}
+	help := k("j/k") + d(": nav") + sep + k("enter") + d(": commit") + sep + k("p") + d(": blame parent") + sep +
+		k("w/m/c") + d(": -w/-M/-C") + sep + k("h") + d(": history") + sep + k("esc") + d(": "+back)
+
+	// Full message of the commit under the cursor
+	var message []string
+	if m.blameCursor < len(m.blameLines) {
+		cur := m.blameLines[m.blameCursor]
+		msg, ok := m.blameMessages[cur.FullHash]
+		switch {
+		case cur.IsUncommitted():
+			msg = "Not committed yet"
+		case !ok:
+			msg = cur.Summary
+		}
+		message = strings.Split(strings.TrimRight(msg, "\n"), "\n")
+		if len(message) > 4 {
+			message = append(message[:3], "…")
+		}
+	}
+
+	maxItems := height - 5 - len(message)
 	if maxItems < 1 {
 		maxItems = 1
 	}
@@ -1418,17 +2357,17 @@ func (m model) renderBlame(width, height int) string {
 			author = author[:10]
 		}
 
-		line := fmt.Sprintf("%s %s %s %s %s",
+		line := fmt.Sprintf("%s %s %s %s ",
 			hashStyle.Render(bl.Hash),
 			authorStyle.Render(fmt.Sprintf("%-10s", author)),
 			dateStyle.Render(bl.Date),
-			lineNumStyle.Render(fmt.Sprintf("%4d", bl.LineNum)),
-			bl.Content)
+			lineNumStyle.Render(fmt.Sprintf("%4d", bl.LineNum)))
 
-		// Truncate if too long
-		if len(line) > width-4 {
-			line = line[:width-7] + "..."
+		content := plainSpans(bl.Content)
+		if i < len(m.blameSyntax) && m.blameSyntax[i] != nil {
+			content = m.blameSyntax[i]
 		}
+		line += fitPieces(content, width-4-lipgloss.Width(line), false)
 
 		if i == m.blameCursor {
 			lines = append(lines, selectedStyle.Width(width-4).Render(line))
@@ -1441,6 +2380,176 @@ func (m model) renderBlame(width, height int) string {
 		lines = append(lines, scrollIndicatorStyle.Render("  ▼ more below"))
 	}
 
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+	for _, ml := range message {
+		lines = append(lines, helpStyle.Render(" "+ml))
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, help)
+
+	return strings.Join(lines, "\n")
+}
+
+// File history view
+
+func (m model) renderFileHistory(width, height int) string {
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+
+	if m.fileLogShow != "" && m.fileLogCursor < len(m.fileLogRevs) {
+		return m.renderFileRevision(width, height)
+	}
+
+	header := sectionHeaderStyle.Render("History: "+m.fileLogPath) + helpStyle.Render(" (following renames)")
+	help := k("enter/d") + d(": diff") + sep + k("o") + d(": file at revision") + sep + k("esc") + d(": back")
+
+	if len(m.fileLogRevs) == 0 {
+		msg := "Loading history..."
+		if m.fileLogPager.done {
+			msg = "No committed history for this file."
+		}
+		return header + "\n" + helpStyle.Render(strings.Repeat("─", width-6)) + "\n\n" +
+			helpStyle.Render(msg) + "\n\n" + help
+	}
+
+	maxItems := height - 4
+	if maxItems < 1 {
+		maxItems = 1
+	}
+
+	hasTop := m.fileLogOffset > 0
+	hasBottom := m.fileLogOffset+maxItems < len(m.fileLogRevs) || !m.fileLogPager.done
+
+	if hasTop {
+		maxItems--
+	}
+	if hasBottom {
+		maxItems--
+	}
+
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	if hasTop {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▲ more above"))
+	}
+
+	endIdx := m.fileLogOffset + maxItems
+	if endIdx > len(m.fileLogRevs) {
+		endIdx = len(m.fileLogRevs)
+	}
+
+	hashStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	authorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	dateStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+
+	for i := m.fileLogOffset; i < endIdx; i++ {
+		rev := m.fileLogRevs[i]
+		line := fmt.Sprintf(" %s %s%s  %s %s",
+			hashStyle.Render(rev.Hash),
+			renderRefs(rev.Refs),
+			rev.Message,
+			authorStyle.Render(rev.Author),
+			dateStyle.Render(rev.Date))
+		// Older names before a rename
+		if rev.Path != m.fileLogPath {
+			line += helpStyle.Render("  as " + rev.Path)
+		}
+
+		if i == m.fileLogCursor {
+			lines = append(lines, selectedStyle.Width(width-4).Render(line))
+		} else {
+			lines = append(lines, line)
+		}
+	}
+
+	if hasBottom {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▼ more below"))
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, help)
+
+	return strings.Join(lines, "\n")
+}
+
+// renderFileRevision shows the selected revision's diff or the file content at it
+func (m model) renderFileRevision(width, height int) string {
+	k := func(key string) string { return keyBindStyle.Render(key) }
+	d := func(desc string) string { return keyDescStyle.Render(desc) }
+	sep := keyDescStyle.Render(" | ")
+
+	rev := m.fileLogRevs[m.fileLogCursor]
+	hashStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("39"))
+	title := "Diff: "
+	help := k("o") + d(": file at revision")
+	if m.fileLogShow == "content" {
+		title = "File: "
+		help = k("d") + d(": diff")
+	}
+	header := sectionHeaderStyle.Render(title+rev.Path) + " " + hashStyle.Render("@ "+rev.Hash) + " " + helpStyle.Render(rev.Message)
+	if m.fileLogShow == "diff" {
+		help += sep + k("t") + d(": split/unified")
+	}
+	help += sep + k("j/k") + d(": scroll") + sep + k("esc") + d(": back")
+
+	text := strings.TrimRight(m.fileLogText, "\n")
+	if text == "" {
+		text = "(no changes to this file)"
+	}
+	content := strings.Split(text, "\n")
+	if m.fileLogShow == "diff" && m.fileLogText != "" {
+		content = renderDiffLines(text, width-4, m.diffSplit)
+	}
+
+	maxLines := height - 4
+	if maxLines < 1 {
+		maxLines = 1
+	}
+
+	hasTop := m.scrollOffset > 0
+	hasBottom := m.scrollOffset+maxLines < len(content)
+
+	if hasTop {
+		maxLines--
+	}
+	if hasBottom {
+		maxLines--
+	}
+
+	var lines []string
+	lines = append(lines, header)
+	lines = append(lines, helpStyle.Render(strings.Repeat("─", width-6)))
+
+	if hasTop {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▲ more above"))
+	}
+
+	endIdx := m.scrollOffset + maxLines
+	if endIdx > len(content) {
+		endIdx = len(content)
+	}
+
+	lineNumStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	for i := min(m.scrollOffset, len(content)); i < endIdx; i++ {
+		if m.fileLogShow == "content" {
+			text := plainSpans(content[i])
+			if i < len(m.fileLogSyntax) && m.fileLogSyntax[i] != nil {
+				text = m.fileLogSyntax[i]
+			}
+			lines = append(lines, lineNumStyle.Render(fmt.Sprintf("%4d ", i+1))+fitPieces(text, width-9, false))
+		} else {
+			lines = append(lines, content[i])
+		}
+	}
+
+	if hasBottom {
+		lines = append(lines, scrollIndicatorStyle.Render("  ▼ more below"))
+	}
+
 	lines = append(lines, "")
 	lines = append(lines, help)
 